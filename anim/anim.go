@@ -0,0 +1,60 @@
+package anim
+
+import (
+	"fmt"
+	"image"
+	"time"
+)
+
+// most pixels decoded for one animation, over all its frames
+const maxPixels = 100 << 20
+
+// refuses n frames of size too large to decode safely
+func checkSize(size image.Point, n int) error {
+	if int64(size.X)*int64(size.Y)*int64(n) > maxPixels {
+		return fmt.Errorf("%d frames of %dx%d pixels is too large to decode, the limit is %d megapixels", n, size.X, size.Y, maxPixels>>20)
+	}
+	return nil
+}
+
+// browsers show gif frames with a delay this short for minDelayShown
+var (
+	minDelay      = 20 * time.Millisecond
+	minDelayShown = 100 * time.Millisecond
+)
+
+// how long a frame with delay d is shown
+func Shown(d time.Duration) time.Duration {
+	if d < minDelay {
+		return minDelayShown
+	}
+	return d
+}
+
+// length of one loop through frames with delays
+func Length(delays []time.Duration) time.Duration {
+	var total time.Duration
+	for _, d := range delays {
+		total += Shown(d)
+	}
+	return total
+}
+
+// index of the frame shown t into a looping animation
+func FrameAt(delays []time.Duration, t time.Duration) int {
+	total := Length(delays)
+	if total <= 0 {
+		return 0
+	}
+	t %= total
+	if t < 0 {
+		t += total
+	}
+	for i, d := range delays {
+		t -= Shown(d)
+		if t < 0 {
+			return i
+		}
+	}
+	return len(delays) - 1
+}
@@ -0,0 +1,32 @@
+package anim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrameAt(t *testing.T) {
+	ms := time.Millisecond
+	delays := []time.Duration{100 * ms, 0, 50 * ms}
+	tests := []struct {
+		t    time.Duration
+		want int
+	}{
+		{0, 0},
+		{99 * ms, 0},
+		{100 * ms, 1},
+		// a zero delay is shown for 100ms
+		{199 * ms, 1},
+		{200 * ms, 2},
+		{250 * ms, 0},
+		{-10 * ms, 2},
+	}
+	for _, tt := range tests {
+		if got := FrameAt(delays, tt.t); got != tt.want {
+			t.Errorf("FrameAt(%v) = %d, want %d", tt.t, got, tt.want)
+		}
+	}
+	if got := Length(delays); got != 250*ms {
+		t.Errorf("Length = %v, want 250ms", got)
+	}
+}
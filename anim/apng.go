@@ -0,0 +1,220 @@
+package anim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"time"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+type chunk struct {
+	typ  string
+	data []byte
+}
+
+// apng frame control
+type fcTL struct {
+	rect      image.Rectangle
+	delay     time.Duration
+	dispose   byte
+	blend     byte
+	data      [][]byte
+	isDefault bool
+}
+
+const (
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+	apngBlendOver         = 1
+)
+
+// reports whether data is a png with an animation control chunk
+func IsAPNG(data []byte) bool {
+	chunks, err := readChunks(data)
+	if err != nil {
+		return false
+	}
+	for _, c := range chunks {
+		switch c.typ {
+		case "acTL":
+			return true
+		case "IDAT":
+			return false
+		}
+	}
+	return false
+}
+
+// decodes every frame of an animated png, composed like DecodeGIF, and
+// how long each frame is shown
+func DecodeAPNG(r io.Reader) ([]*image.RGBA, []time.Duration, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunks, err := readChunks(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || len(chunks[0].data) < 8 {
+		return nil, nil, errors.New("apng: missing IHDR")
+	}
+	ihdr := chunks[0]
+	width := int(binary.BigEndian.Uint32(ihdr.data[0:]))
+	height := int(binary.BigEndian.Uint32(ihdr.data[4:]))
+	bounds := image.Rect(0, 0, width, height)
+	if err := checkSize(bounds.Size(), 1); err != nil {
+		return nil, nil, err
+	}
+
+	// chunks every frame needs to decode, like the palette
+	shared := []chunk{}
+	frames := []*fcTL{}
+	var cur *fcTL
+	seenIDAT := false
+	for _, c := range chunks[1:] {
+		switch c.typ {
+		case "fcTL":
+			f, err := parseFcTL(c.data)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !f.rect.In(bounds) {
+				return nil, nil, errors.New("apng: frame outside the image")
+			}
+			f.isDefault = !seenIDAT
+			frames = append(frames, f)
+			cur = f
+		case "IDAT":
+			seenIDAT = true
+			if cur != nil && cur.isDefault {
+				cur.data = append(cur.data, c.data)
+			}
+		case "fdAT":
+			if cur == nil || len(c.data) < 4 {
+				return nil, nil, errors.New("apng: fdAT without fcTL")
+			}
+			cur.data = append(cur.data, c.data[4:])
+		case "acTL", "IEND":
+		default:
+			if !seenIDAT {
+				shared = append(shared, c)
+			}
+		}
+	}
+	if len(frames) == 0 {
+		return nil, nil, errors.New("apng: no frames")
+	}
+	if err := checkSize(bounds.Size(), len(frames)); err != nil {
+		return nil, nil, err
+	}
+
+	screen := image.NewRGBA(bounds)
+	imgs := make([]*image.RGBA, 0, len(frames))
+	delays := make([]time.Duration, 0, len(frames))
+	for i, f := range frames {
+		img, err := decodeFrame(ihdr, shared, f)
+		if err != nil {
+			return nil, nil, err
+		}
+		dispose := f.dispose
+		if i == 0 && dispose == apngDisposePrevious {
+			dispose = apngDisposeBackground
+		}
+		var prev *image.RGBA
+		if dispose == apngDisposePrevious {
+			prev = copyRGBA(screen)
+		}
+		op := draw.Src
+		if f.blend == apngBlendOver {
+			op = draw.Over
+		}
+		draw.Draw(screen, f.rect, img, img.Bounds().Min, op)
+		imgs = append(imgs, copyRGBA(screen))
+		delays = append(delays, f.delay)
+
+		switch dispose {
+		case apngDisposeBackground:
+			draw.Draw(screen, f.rect, image.Transparent, image.Point{}, draw.Src)
+		case apngDisposePrevious:
+			screen = prev
+		}
+	}
+	return imgs, delays, nil
+}
+
+func parseFcTL(b []byte) (*fcTL, error) {
+	if len(b) < 26 {
+		return nil, errors.New("apng: short fcTL")
+	}
+	be := binary.BigEndian
+	w, h := int(be.Uint32(b[4:])), int(be.Uint32(b[8:]))
+	x, y := int(be.Uint32(b[12:])), int(be.Uint32(b[16:]))
+	num, den := be.Uint16(b[20:]), be.Uint16(b[22:])
+	if den == 0 {
+		den = 100
+	}
+	return &fcTL{
+		rect:    image.Rect(x, y, x+w, y+h),
+		delay:   time.Duration(num) * time.Second / time.Duration(den),
+		dispose: b[24],
+		blend:   b[25],
+	}, nil
+}
+
+// decodes one frame by rebuilding it as a plain png
+func decodeFrame(ihdr chunk, shared []chunk, f *fcTL) (image.Image, error) {
+	var buf bytes.Buffer
+	buf.Write(pngHeader)
+	hdr := append([]byte{}, ihdr.data...)
+	binary.BigEndian.PutUint32(hdr[0:], uint32(f.rect.Dx()))
+	binary.BigEndian.PutUint32(hdr[4:], uint32(f.rect.Dy()))
+	writeChunk(&buf, "IHDR", hdr)
+	for _, c := range shared {
+		writeChunk(&buf, c.typ, c.data)
+	}
+	writeChunk(&buf, "IDAT", bytes.Join(f.data, nil))
+	writeChunk(&buf, "IEND", nil)
+	return png.Decode(&buf)
+}
+
+func readChunks(data []byte) ([]chunk, error) {
+	if !bytes.HasPrefix(data, pngHeader) {
+		return nil, errors.New("apng: not a png")
+	}
+	data = data[len(pngHeader):]
+	chunks := []chunk{}
+	for len(data) >= 12 {
+		n := int(binary.BigEndian.Uint32(data))
+		if n < 0 || 12+n > len(data) {
+			return nil, errors.New("apng: truncated chunk")
+		}
+		c := chunk{typ: string(data[4:8]), data: data[8 : 8+n]}
+		chunks = append(chunks, c)
+		data = data[12+n:]
+		if c.typ == "IEND" {
+			break
+		}
+	}
+	return chunks, nil
+}
+
+func writeChunk(w *bytes.Buffer, typ string, data []byte) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(data)))
+	w.Write(n[:])
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	w.WriteString(typ)
+	w.Write(data)
+	binary.BigEndian.PutUint32(n[:], crc.Sum32())
+	w.Write(n[:])
+}
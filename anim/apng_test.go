@@ -0,0 +1,120 @@
+package anim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fill(r image.Rectangle, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(r)
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+// chunks of a png encoding of img, without the signature
+func encodeChunks(t *testing.T, img image.Image) []chunk {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := readChunks(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chunks
+}
+
+func fcTLData(seq uint32, r image.Rectangle, delayMs uint16, dispose, blend byte) []byte {
+	b := make([]byte, 26)
+	be := binary.BigEndian
+	be.PutUint32(b[0:], seq)
+	be.PutUint32(b[4:], uint32(r.Dx()))
+	be.PutUint32(b[8:], uint32(r.Dy()))
+	be.PutUint32(b[12:], uint32(r.Min.X))
+	be.PutUint32(b[16:], uint32(r.Min.Y))
+	be.PutUint16(b[20:], delayMs)
+	be.PutUint16(b[22:], 1000)
+	b[24], b[25] = dispose, blend
+	return b
+}
+
+func TestDecodeAPNG(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	first := encodeChunks(t, fill(image.Rect(0, 0, 3, 3), red))
+	patch := image.Rect(1, 1, 2, 2)
+	second := encodeChunks(t, fill(image.Rect(0, 0, 1, 1), blue))
+
+	var buf bytes.Buffer
+	buf.Write(pngHeader)
+	writeChunk(&buf, "IHDR", first[0].data)
+	writeChunk(&buf, "acTL", []byte{0, 0, 0, 2, 0, 0, 0, 0})
+	writeChunk(&buf, "fcTL", fcTLData(0, image.Rect(0, 0, 3, 3), 50, 0, 0))
+	for _, c := range first[1:] {
+		if c.typ == "IDAT" {
+			writeChunk(&buf, "IDAT", c.data)
+		}
+	}
+	writeChunk(&buf, "fcTL", fcTLData(1, patch, 250, apngDisposeBackground, apngBlendOver))
+	for _, c := range second[1:] {
+		if c.typ == "IDAT" {
+			writeChunk(&buf, "fdAT", append([]byte{0, 0, 0, 2}, c.data...))
+		}
+	}
+	writeChunk(&buf, "IEND", nil)
+
+	if !IsAPNG(buf.Bytes()) {
+		t.Fatal("IsAPNG = false")
+	}
+	frames, delays, err := DecodeAPNG(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 {
+		t.Fatalf("got %d frames, want 2", len(frames))
+	}
+	if got := frames[0].RGBAAt(1, 1); got != red {
+		t.Errorf("frame 0 = %v, want %v", got, red)
+	}
+	if got := frames[1].RGBAAt(1, 1); got != blue {
+		t.Errorf("frame 1 = %v, want %v", got, blue)
+	}
+	if got := frames[1].RGBAAt(0, 0); got != red {
+		t.Errorf("frame 1 outside patch = %v, want %v", got, red)
+	}
+	if delays[0] != 50*time.Millisecond || delays[1] != 250*time.Millisecond {
+		t.Errorf("delays = %v", delays)
+	}
+
+	// a huge canvas is refused before anything is allocated
+	binary.BigEndian.PutUint32(first[0].data[0:], 100000)
+	binary.BigEndian.PutUint32(first[0].data[4:], 100000)
+	buf.Reset()
+	buf.Write(pngHeader)
+	writeChunk(&buf, "IHDR", first[0].data)
+	writeChunk(&buf, "acTL", []byte{0, 0, 0, 1, 0, 0, 0, 0})
+	writeChunk(&buf, "fcTL", fcTLData(0, image.Rect(0, 0, 1, 1), 50, 0, 0))
+	for _, c := range second[1:] {
+		if c.typ == "IDAT" {
+			writeChunk(&buf, "IDAT", c.data)
+		}
+	}
+	writeChunk(&buf, "IEND", nil)
+	if _, _, err := DecodeAPNG(&buf); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("100000x100000 apng: %v", err)
+	}
+
+	var still bytes.Buffer
+	png.Encode(&still, fill(image.Rect(0, 0, 1, 1), red))
+	if IsAPNG(still.Bytes()) {
+		t.Error("IsAPNG(still png) = true")
+	}
+}
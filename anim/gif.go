@@ -0,0 +1,98 @@
+// Package anim decodes and encodes frame sequences
+package anim
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// decodes every frame of a gif, each drawn over the previous ones as a
+// viewer would show it, and how long each frame is shown
+func DecodeGIF(r io.Reader) ([]*image.RGBA, []time.Duration, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the logical screen is checked before decoding any frame
+	c, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkSize(image.Pt(c.Width, c.Height), 1); err != nil {
+		return nil, nil, err
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() && len(g.Image) > 0 {
+		bounds = g.Image[0].Bounds()
+	}
+	if err := checkSize(bounds.Size(), len(g.Image)); err != nil {
+		return nil, nil, err
+	}
+	screen := image.NewRGBA(bounds)
+	frames := make([]*image.RGBA, 0, len(g.Image))
+	delays := make([]time.Duration, 0, len(g.Image))
+	for i, p := range g.Image {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var prev *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			prev = copyRGBA(screen)
+		}
+		draw.Draw(screen, p.Bounds(), p, p.Bounds().Min, draw.Over)
+		frames = append(frames, copyRGBA(screen))
+		delay := 0
+		if i < len(g.Delay) {
+			delay = g.Delay[i]
+		}
+		delays = append(delays, time.Duration(delay)*10*time.Millisecond)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(screen, p.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			screen = prev
+		}
+	}
+	return frames, delays, nil
+}
+
+// encodes frames as a looping gif, dithered to the plan 9 palette. each
+// frame is shown for its delay, rounded to a hundredth of a second
+func EncodeGIF(w io.Writer, frames []image.Image, delays []time.Duration) error {
+	if len(frames) == 0 {
+		return errors.New("gif: no frames")
+	}
+	g := &gif.GIF{}
+	for i, f := range frames {
+		p := image.NewPaletted(f.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(p, p.Bounds(), f, f.Bounds().Min)
+		g.Image = append(g.Image, p)
+		delay := 0
+		if i < len(delays) {
+			delay = int((delays[i] + 5*time.Millisecond) / (10 * time.Millisecond))
+		}
+		if delay < 2 {
+			delay = 2
+		}
+		g.Delay = append(g.Delay, delay)
+	}
+	return gif.EncodeAll(w, g)
+}
+
+func copyRGBA(img *image.RGBA) *image.RGBA {
+	c := image.NewRGBA(img.Bounds())
+	copy(c.Pix, img.Pix)
+	return c
+}
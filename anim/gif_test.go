@@ -0,0 +1,116 @@
+package anim
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+func TestDecodeGIF(t *testing.T) {
+	pal := color.Palette{color.Transparent, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	full := image.NewPaletted(image.Rect(0, 0, 4, 4), pal)
+	for i := range full.Pix {
+		full.Pix[i] = 1
+	}
+	// a blue pixel drawn over the red frame
+	patch := image.NewPaletted(image.Rect(1, 1, 2, 2), pal)
+	patch.Pix[0] = 2
+	// cleared to transparent after being shown
+	cleared := image.NewPaletted(image.Rect(2, 2, 3, 3), pal)
+	cleared.Pix[0] = 2
+	last := image.NewPaletted(image.Rect(0, 0, 1, 1), pal)
+
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:    []*image.Paletted{full, patch, cleared, last},
+		Delay:    []int{10, 20, 30, 40},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalNone, gif.DisposalBackground, gif.DisposalNone},
+		Config:   image.Config{ColorModel: pal, Width: 4, Height: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames, delays, err := DecodeGIF(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 4 {
+		t.Fatalf("got %d frames, want 4", len(frames))
+	}
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	tests := []struct {
+		frame int
+		x, y  int
+		want  color.RGBA
+	}{
+		{0, 1, 1, red},
+		{1, 1, 1, blue},
+		{1, 0, 0, red},
+		{2, 2, 2, blue},
+		{2, 1, 1, blue},
+		{3, 2, 2, color.RGBA{}},
+		{3, 1, 1, blue},
+	}
+	for _, tt := range tests {
+		if got := frames[tt.frame].RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("frame %d at %d,%d = %v, want %v", tt.frame, tt.x, tt.y, got, tt.want)
+		}
+	}
+	if delays[1] != 200*time.Millisecond {
+		t.Errorf("delay = %v, want 200ms", delays[1])
+	}
+}
+
+func TestDecodeGIFTooLarge(t *testing.T) {
+	pal := color.Palette{color.Transparent, color.White}
+	dot := image.NewPaletted(image.Rect(0, 0, 1, 1), pal)
+	for _, tc := range []struct {
+		size, frames int
+	}{
+		// a huge screen with tiny frames
+		{60000, 1},
+		// many frames that each fit
+		{1000, 200},
+	} {
+		g := &gif.GIF{Config: image.Config{ColorModel: pal, Width: tc.size, Height: tc.size}}
+		for i := 0; i < tc.frames; i++ {
+			g.Image = append(g.Image, dot)
+			g.Delay = append(g.Delay, 10)
+		}
+		var buf bytes.Buffer
+		if err := gif.EncodeAll(&buf, g); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := DecodeGIF(&buf); err == nil {
+			t.Errorf("%d frames of %dpx decoded", tc.frames, tc.size)
+		}
+	}
+}
+
+func TestEncodeGIF(t *testing.T) {
+	frames := []image.Image{
+		fill(image.Rect(0, 0, 2, 2), color.RGBA{255, 255, 255, 255}),
+		fill(image.Rect(0, 0, 2, 2), color.RGBA{0, 0, 0, 255}),
+	}
+	var buf bytes.Buffer
+	if err := EncodeGIF(&buf, frames, []time.Duration{120 * time.Millisecond, 0}); err != nil {
+		t.Fatal(err)
+	}
+	got, delays, err := DecodeGIF(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d frames, want 2", len(got))
+	}
+	if c := got[1].RGBAAt(0, 0); c != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("frame 1 = %v, want black", c)
+	}
+	if delays[0] != 120*time.Millisecond || delays[1] != 20*time.Millisecond {
+		t.Errorf("delays = %v", delays)
+	}
+}
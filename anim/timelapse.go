@@ -0,0 +1,81 @@
+package anim
+
+import (
+	"bytes"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"sync"
+)
+
+// frames of a canvas captured as it changes. frames are dithered to the
+// plan 9 palette to keep them small, and thinned to every other one past
+// a limit. safe for use from several goroutines
+type Timelapse struct {
+	m      sync.Mutex
+	frames []*image.Paletted
+	max    int
+}
+
+// keeps at most max frames, at least 2
+func NewTimelapse(max int) *Timelapse {
+	if max < 2 {
+		max = 2
+	}
+	return &Timelapse{max: max}
+}
+
+// adds img as the newest frame, unless it matches the last one
+func (t *Timelapse) Add(img image.Image) {
+	p := image.NewPaletted(img.Bounds(), palette.Plan9)
+	draw.FloydSteinberg.Draw(p, p.Bounds(), img, img.Bounds().Min)
+	t.m.Lock()
+	defer t.m.Unlock()
+	if n := len(t.frames); n > 0 {
+		last := t.frames[n-1]
+		if last.Rect == p.Rect && bytes.Equal(last.Pix, p.Pix) {
+			return
+		}
+	}
+	t.frames = append(t.frames, p)
+	if len(t.frames) <= t.max {
+		return
+	}
+	// the newest frame always stays
+	kept := t.frames[:0]
+	for i, f := range t.frames {
+		if i%2 == 0 || i == len(t.frames)-1 {
+			kept = append(kept, f)
+		}
+	}
+	for i := len(kept); i < len(t.frames); i++ {
+		t.frames[i] = nil
+	}
+	t.frames = kept
+}
+
+func (t *Timelapse) Len() int {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return len(t.frames)
+}
+
+// at most n frames spread evenly over the recording, the first and last
+// included. all of them for n below 1
+func (t *Timelapse) Frames(n int) []image.Image {
+	t.m.Lock()
+	defer t.m.Unlock()
+	count := len(t.frames)
+	if n < 1 || n > count {
+		n = count
+	}
+	frames := make([]image.Image, n)
+	for i := range frames {
+		j := 0
+		if n > 1 {
+			j = i * (count - 1) / (n - 1)
+		}
+		frames[i] = t.frames[j]
+	}
+	return frames
+}
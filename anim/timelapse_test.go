@@ -0,0 +1,68 @@
+package anim
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"testing"
+)
+
+// a 2x2 frame in the i-th color of the palette
+func frame(i int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			img.Set(x, y, palette.Plan9[i])
+		}
+	}
+	return img
+}
+
+func index(t *testing.T, img image.Image) int {
+	t.Helper()
+	return color.Palette(palette.Plan9).Index(img.At(0, 0))
+}
+
+func TestTimelapse(t *testing.T) {
+	tl := NewTimelapse(4)
+	tl.Add(frame(1))
+	tl.Add(frame(1))
+	if tl.Len() != 1 {
+		t.Fatalf("a repeated frame was kept, len %d", tl.Len())
+	}
+	for i := 2; i <= 5; i++ {
+		tl.Add(frame(i))
+	}
+	// 5 frames thin to 1, 3 and the newest, 5
+	got := []int{}
+	for _, f := range tl.Frames(0) {
+		got = append(got, index(t, f))
+	}
+	if want := []int{1, 3, 5}; !equalInts(got, want) {
+		t.Errorf("frames %v, want %v", got, want)
+	}
+	tl.Add(frame(6))
+	tl.Add(frame(7))
+	got = got[:0]
+	for _, f := range tl.Frames(3) {
+		got = append(got, index(t, f))
+	}
+	if want := []int{1, 5, 7}; !equalInts(got, want) {
+		t.Errorf("sampled %v, want %v", got, want)
+	}
+	if _, ok := tl.Frames(1)[0].(*image.Paletted); !ok {
+		t.Error("frames are not paletted")
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -2,9 +2,11 @@ package canvas
 
 import (
 	"frame/draw"
+	"frame/meta"
 	"frame/sprite"
 	"image"
 	"image/color"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -32,6 +34,8 @@ func NewCanvas(width, height int) *Canvas {
 }
 
 func (c *Canvas) Resize(width, height int) {
+	c.Width = width
+	c.Height = height
 	i := ebiten.NewImage(width, height)
 	i.DrawImage(c.image, nil)
 	c.image = i
@@ -45,6 +49,13 @@ func (c *Canvas) DrawSprites() {
 	}
 }
 
+// shows the frame of every animated sprite due t into its loop
+func (c *Canvas) Animate(t time.Duration) {
+	for _, s := range c.Sprites {
+		s.Animate(t)
+	}
+}
+
 func (c Canvas) Image() *ebiten.Image {
 	return c.image
 }
@@ -84,13 +95,39 @@ func (c *Canvas) Reorder(command sprite.ReorderCommand, s *sprite.Sprite) {
 	c.Sprites = newList
 }
 
+// reorders several sprites, keeping their order among themselves
+func (c *Canvas) ReorderAll(command sprite.ReorderCommand, sprites []*sprite.Sprite) {
+	switch command {
+	case sprite.ReorderBringForwards:
+		c.Sprites = c.Sprites.BringAllForwards(sprites)
+	case sprite.ReorderSendBackwards:
+		c.Sprites = c.Sprites.SendAllBackwards(sprites)
+	case sprite.ReorderBringToFront:
+		c.Sprites = c.Sprites.BringAllToFront(sprites)
+	case sprite.ReorderSendToBack:
+		c.Sprites = c.Sprites.SendAllToBack(sprites)
+	}
+}
+
 func (c *Canvas) NewSpriteFromRegion(r image.Rectangle) *sprite.Sprite {
 	im, r := draw.CropImage(c.image, r, image.Point{0, 0})
 	if im == nil {
 		return nil
 	}
 	return &sprite.Sprite{
-		Image: im,
-		Pos:   r.Min,
+		Image:   im,
+		Pos:     r.Min,
+		Credits: c.Credits(r),
+	}
+}
+
+// sources of the sprites that show in r, front to back
+func (c *Canvas) Credits(r image.Rectangle) []meta.Credit {
+	lists := [][]meta.Credit{}
+	for _, s := range c.Sprites {
+		if s.Overlaps(r) && s.OpacityOffset > -1 {
+			lists = append(lists, s.Credits)
+		}
 	}
+	return meta.Merge(lists...)
 }
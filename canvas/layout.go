@@ -0,0 +1,71 @@
+package canvas
+
+import (
+	"fmt"
+	"frame/sprite"
+	"image"
+)
+
+type Layout int
+
+const (
+	LayoutNone Layout = iota
+	LayoutCascade
+	LayoutGrid
+)
+
+var (
+	cascadeStep = 24
+	gridGap     = 8
+)
+
+func (l Layout) String() string {
+	switch l {
+	case LayoutCascade:
+		return "cascade"
+	case LayoutGrid:
+		return "grid"
+	default:
+		return "none"
+	}
+}
+
+func ParseLayout(s string) (Layout, error) {
+	for _, l := range []Layout{LayoutNone, LayoutCascade, LayoutGrid} {
+		if l.String() == s {
+			return l, nil
+		}
+	}
+	return LayoutNone, fmt.Errorf("unknown layout %q, want none, cascade or grid", s)
+}
+
+// places sprites starting at p, in the order given. cascade steps each
+// sprite down and right, grid fills rows up to the canvas width
+func (c *Canvas) Arrange(sprites []*sprite.Sprite, layout Layout, p image.Point) {
+	switch layout {
+	case LayoutCascade:
+		for i, sp := range sprites {
+			sp.Pos = CascadePos(p, i)
+		}
+	case LayoutGrid:
+		pos := p
+		rowHeight := 0
+		for _, sp := range sprites {
+			size := sp.Image.Bounds().Size()
+			if pos.X > p.X && pos.X+size.X > c.Width {
+				pos = image.Pt(p.X, pos.Y+rowHeight+gridGap)
+				rowHeight = 0
+			}
+			sp.Pos = pos
+			pos.X += size.X + gridGap
+			if size.Y > rowHeight {
+				rowHeight = size.Y
+			}
+		}
+	}
+}
+
+// position of the i-th sprite of a cascade starting at p
+func CascadePos(p image.Point, i int) image.Point {
+	return p.Add(image.Pt(i*cascadeStep, i*cascadeStep))
+}
@@ -0,0 +1,376 @@
+// Package collab connects frame instances so they can edit one canvas
+// together. One instance hosts, the others join. The host orders every
+// message: messages from guests are delivered to the host and relayed to
+// every guest, the sender included, as the host polls them, so all peers
+// apply the same changes in the same order. Changes are never dropped, only
+// selection updates are. Sending never blocks, a peer that stops reading for
+// writeTimeout is disconnected.
+package collab
+
+import (
+	"encoding/gob"
+	"errors"
+	"image"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+type Kind int
+
+const (
+	KindJoin Kind = iota + 1
+	KindLeave
+	KindSnapshot
+	KindSprite
+	KindDelete
+	KindOrder
+	KindSelection
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindJoin:
+		return "join"
+	case KindLeave:
+		return "leave"
+	case KindSnapshot:
+		return "snapshot"
+	case KindSprite:
+		return "sprite"
+	case KindDelete:
+		return "delete"
+	case KindOrder:
+		return "order"
+	case KindSelection:
+		return "selection"
+	default:
+		return "unknown"
+	}
+}
+
+type Msg struct {
+	Kind Kind
+	// peer the message originated from
+	From uint32
+	// if set, the host only sends the message to this peer
+	To uint32
+
+	// KindSprite adds or updates a sprite, KindDelete removes ID
+	Sprite SpriteState
+	ID     uint64
+	// KindSnapshot, front to back
+	Sprites []SpriteState
+	// KindOrder, sprite ids front to back
+	Order []uint64
+	// KindSelection, outlines of the sender's selection
+	Rects []image.Rectangle
+}
+
+type SpriteState struct {
+	ID uint64
+	// nil when only the position or opacity changed
+	Image *image.RGBA
+	// every frame of an animated sprite, sent along with Image
+	Frames        []*image.RGBA
+	Delays        []time.Duration
+	Pos           image.Point
+	OpacityOffset float64
+}
+
+type Session struct {
+	Self uint32
+
+	host  bool
+	ln    net.Listener
+	in    chan Msg
+	m     sync.Mutex
+	peers map[*peer]bool
+	// guests only, connection to the host
+	upstream *peer
+	closed   bool
+	// closed by Close, unblocks delivery
+	done chan struct{}
+	// writeTimeout when the session started
+	timeout time.Duration
+}
+
+type peer struct {
+	id   uint32
+	conn net.Conn
+	m    sync.Mutex
+	// messages the writer has yet to send
+	pending []Msg
+	// signalled when pending grows
+	ready chan struct{}
+	// closed when the peer is dropped, ends its writer
+	done chan struct{}
+	once sync.Once
+}
+
+func (p *peer) stop() {
+	p.once.Do(func() {
+		close(p.done)
+		p.conn.Close()
+	})
+}
+
+// queues m for the writer without blocking. a selection replaces an unsent
+// one from the same peer, anything else is kept
+func (p *peer) queue(m Msg) {
+	p.m.Lock()
+	replaced := false
+	if m.Kind == KindSelection {
+		for i, q := range p.pending {
+			if q.Kind == KindSelection && q.From == m.From {
+				p.pending[i] = m
+				replaced = true
+				break
+			}
+		}
+	}
+	if !replaced {
+		p.pending = append(p.pending, m)
+	}
+	p.m.Unlock()
+	select {
+	case p.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (p *peer) take() []Msg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	pending := p.pending
+	p.pending = nil
+	return pending
+}
+
+const queueSize = 256
+
+// how long a peer may stop reading before it is disconnected
+var writeTimeout = 30 * time.Second
+
+func newSession(host bool) *Session {
+	var self uint32
+	for self == 0 {
+		self = rand.Uint32()
+	}
+	return &Session{
+		Self:    self,
+		host:    host,
+		in:      make(chan Msg, queueSize),
+		peers:   map[*peer]bool{},
+		done:    make(chan struct{}),
+		timeout: writeTimeout,
+	}
+}
+
+// listens on addr for guests
+func Host(addr string) (*Session, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := newSession(true)
+	s.ln = ln
+	go s.accept()
+	return s, nil
+}
+
+// connects to the host at addr and announces this peer. the host answers
+// with a snapshot of its canvas
+func Join(addr string) (*Session, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := newSession(false)
+	s.upstream = s.addPeer(conn)
+	s.Send(Msg{Kind: KindJoin})
+	return s, nil
+}
+
+func (s *Session) IsHost() bool {
+	return s.host
+}
+
+// address the host listens on
+func (s *Session) Addr() net.Addr {
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+func (s *Session) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Println("collab:", err)
+			}
+			return
+		}
+		s.addPeer(conn)
+	}
+}
+
+func (s *Session) addPeer(conn net.Conn) *peer {
+	p := &peer{
+		conn:  conn,
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	s.m.Lock()
+	s.peers[p] = true
+	s.m.Unlock()
+	go s.write(p)
+	go s.read(p)
+	return p
+}
+
+func (s *Session) write(p *peer) {
+	enc := gob.NewEncoder(p.conn)
+	for {
+		select {
+		case <-p.ready:
+		case <-p.done:
+			return
+		}
+		for _, m := range p.take() {
+			p.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+			if err := enc.Encode(m); err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Println("collab:", err)
+				}
+				// the reader sees the closed connection and drops the peer
+				p.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (s *Session) read(p *peer) {
+	dec := gob.NewDecoder(p.conn)
+	for {
+		var m Msg
+		if err := dec.Decode(&m); err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Println("collab:", err)
+			}
+			s.drop(p)
+			return
+		}
+		if s.host && m.Kind == KindJoin {
+			p.id = m.From
+		}
+		s.deliver(m)
+	}
+}
+
+// waits while the game loop is behind, so changes are never lost. a
+// selection is dropped instead, a later one replaces it
+func (s *Session) deliver(m Msg) {
+	if m.Kind == KindSelection {
+		select {
+		case s.in <- m:
+		default:
+		}
+		return
+	}
+	select {
+	case s.in <- m:
+	case <-s.done:
+	}
+}
+
+// sends m to every guest it is for
+func (s *Session) relay(m Msg) {
+	s.m.Lock()
+	peers := []*peer{}
+	for p := range s.peers {
+		if m.To != 0 && m.To != p.id {
+			continue
+		}
+		peers = append(peers, p)
+	}
+	s.m.Unlock()
+	for _, p := range peers {
+		p.queue(m)
+	}
+}
+
+func (s *Session) drop(p *peer) {
+	s.m.Lock()
+	if !s.peers[p] {
+		s.m.Unlock()
+		return
+	}
+	delete(s.peers, p)
+	s.m.Unlock()
+	p.stop()
+	// from 0 means the host went away
+	s.deliver(Msg{Kind: KindLeave, From: p.id})
+}
+
+// sends a local change. guests send to the host, the host to its guests
+func (s *Session) Send(m Msg) {
+	m.From = s.Self
+	if s.host {
+		s.relay(m)
+		return
+	}
+	s.m.Lock()
+	connected := s.peers[s.upstream]
+	s.m.Unlock()
+	if connected {
+		s.upstream.queue(m)
+	}
+}
+
+// returns the next received message without blocking. the host relays it
+// to its guests as it is returned, so they see the order the host applies
+func (s *Session) Poll() (Msg, bool) {
+	select {
+	case m := <-s.in:
+		if s.host {
+			s.relay(m)
+		}
+		return m, true
+	default:
+		return Msg{}, false
+	}
+}
+
+// number of connected peers
+func (s *Session) Peers() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return len(s.peers)
+}
+
+func (s *Session) Close() error {
+	s.m.Lock()
+	if !s.closed {
+		close(s.done)
+	}
+	s.closed = true
+	peers := []*peer{}
+	for p := range s.peers {
+		peers = append(peers, p)
+	}
+	s.m.Unlock()
+	for _, p := range peers {
+		p.stop()
+	}
+	if s.ln != nil {
+		return s.ln.Close()
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package collab
+
+import (
+	"encoding/gob"
+	"image"
+	"net"
+	"testing"
+	"time"
+)
+
+func next(t *testing.T, s *Session) Msg {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if m, ok := s.Poll(); ok {
+			return m
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("no message")
+	return Msg{}
+}
+
+func TestSession(t *testing.T) {
+	host, err := Host("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+	guest, err := Join(host.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer guest.Close()
+
+	m := next(t, host)
+	if m.Kind != KindJoin || m.From != guest.Self {
+		t.Fatalf("host got %v from %d, want join from %d", m.Kind, m.From, guest.Self)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Pix[3] = 255
+	host.Send(Msg{
+		Kind:    KindSnapshot,
+		To:      m.From,
+		Sprites: []SpriteState{{ID: 1, Image: img, Pos: image.Pt(3, 4)}},
+	})
+	if m := next(t, guest); m.Kind != KindJoin || m.From != guest.Self {
+		t.Fatalf("guest got %v from %d, want its own join", m.Kind, m.From)
+	}
+	m = next(t, guest)
+	if m.Kind != KindSnapshot || len(m.Sprites) != 1 {
+		t.Fatalf("guest got %v with %d sprites, want snapshot", m.Kind, len(m.Sprites))
+	}
+	if s := m.Sprites[0]; s.Pos != image.Pt(3, 4) || s.Image.Pix[3] != 255 {
+		t.Errorf("snapshot sprite = %+v", s)
+	}
+
+	other, err := Join(host.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if m := next(t, host); m.Kind != KindJoin || m.From != other.Self {
+		t.Fatalf("host got %v from %d, want join from %d", m.Kind, m.From, other.Self)
+	}
+	if m := next(t, guest); m.Kind != KindJoin || m.From != other.Self {
+		t.Fatalf("guest got %v from %d, want join from %d", m.Kind, m.From, other.Self)
+	}
+
+	// guest changes are applied by the host and relayed to every guest in
+	// the host's order, the sender included
+	if m := next(t, other); m.Kind != KindJoin || m.From != other.Self {
+		t.Fatalf("other guest got %v from %d, want its own join", m.Kind, m.From)
+	}
+	host.Send(Msg{Kind: KindSprite, Sprite: SpriteState{ID: 1, Pos: image.Pt(5, 5)}})
+	guest.Send(Msg{Kind: KindSprite, Sprite: SpriteState{ID: 1, Pos: image.Pt(6, 6)}})
+	if m := next(t, host); m.Kind != KindSprite || m.From != guest.Self {
+		t.Errorf("host got %v from %d, want the guest's sprite", m.Kind, m.From)
+	}
+	for _, s := range []*Session{guest, other} {
+		first, second := next(t, s), next(t, s)
+		if first.Sprite.Pos != image.Pt(5, 5) || second.Sprite.Pos != image.Pt(6, 6) {
+			t.Errorf("guest %d got %v then %v, want the host's order", s.Self, first.Sprite.Pos, second.Sprite.Pos)
+		}
+	}
+
+	guest.Close()
+	if m := next(t, host); m.Kind != KindLeave || m.From != guest.Self {
+		t.Errorf("host got %v from %d, want leave", m.Kind, m.From)
+	}
+}
+
+// changes wait for a slow game loop, selections are dropped
+func TestDeliverKeepsChanges(t *testing.T) {
+	host, err := Host("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+	guest, err := Join(host.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer guest.Close()
+	n := 2 * queueSize
+	for i := 0; i < n; i++ {
+		guest.Send(Msg{Kind: KindSelection})
+		guest.Send(Msg{Kind: KindDelete, ID: uint64(i)})
+	}
+	time.Sleep(100 * time.Millisecond)
+	if m := next(t, host); m.Kind != KindJoin {
+		t.Fatalf("host got %v, want join", m.Kind)
+	}
+	deletes := 0
+	for deletes < n {
+		m := next(t, host)
+		if m.Kind != KindDelete {
+			continue
+		}
+		if m.ID != uint64(deletes) {
+			t.Fatalf("delete %d, want %d", m.ID, deletes)
+		}
+		deletes++
+	}
+}
+
+// a guest that stops reading is disconnected instead of blocking the host
+func TestStalledPeer(t *testing.T) {
+	defer func(d time.Duration) { writeTimeout = d }(writeTimeout)
+	writeTimeout = 100 * time.Millisecond
+	host, err := Host("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+	conn, err := net.Dial("tcp", host.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := gob.NewEncoder(conn).Encode(Msg{Kind: KindJoin, From: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if m := next(t, host); m.Kind != KindJoin {
+		t.Fatalf("host got %v, want join", m.Kind)
+	}
+
+	big := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 4*queueSize; i++ {
+			host.Send(Msg{Kind: KindSprite, Sprite: SpriteState{ID: 1, Image: big}})
+		}
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send blocked on a stalled peer")
+	}
+	if m := next(t, host); m.Kind != KindLeave || m.From != 7 {
+		t.Errorf("host got %v from %d, want leave from 7", m.Kind, m.From)
+	}
+	if n := host.Peers(); n != 0 {
+		t.Errorf("%d peers left", n)
+	}
+}
@@ -0,0 +1,170 @@
+// Package control implements a local socket that lets other programs drive a
+// running frame instance, and the client side used by `frame ctl`.
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	CmdAddImage = "add-image"
+	CmdList     = "list"
+	CmdExport   = "export"
+	CmdClear    = "clear"
+)
+
+type Request struct {
+	Command string `json:"command"`
+	// absolute path of an image to add
+	Path string `json:"path,omitempty"`
+	// encoded image to add, used when Path is empty
+	Data []byte `json:"data,omitempty"`
+	X    int    `json:"x,omitempty"`
+	Y    int    `json:"y,omitempty"`
+}
+
+type Response struct {
+	Error   string       `json:"error,omitempty"`
+	Message string       `json:"message,omitempty"`
+	Sprites []SpriteInfo `json:"sprites,omitempty"`
+	// exported png
+	Data []byte `json:"data,omitempty"`
+}
+
+// sprite as listed by CmdList, front to back
+type SpriteInfo struct {
+	Index   int     `json:"index"`
+	X       int     `json:"x"`
+	Y       int     `json:"y"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Opacity float64 `json:"opacity"`
+}
+
+// handles requests, called from a connection's goroutine
+type Handler interface {
+	Handle(Request) Response
+}
+
+type HandlerFunc func(Request) Response
+
+func (f HandlerFunc) Handle(req Request) Response { return f(req) }
+
+func ErrorResponse(err error) Response {
+	return Response{Error: err.Error()}
+}
+
+type Server struct {
+	ln      net.Listener
+	path    string
+	handler Handler
+	wg      sync.WaitGroup
+}
+
+// socket in the user's runtime directory
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "frame.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("frame-%d.sock", os.Getuid()))
+}
+
+// listens on the unix socket at path. a stale socket file left by a previous
+// instance is removed, a live one is an error
+func Listen(path string, h Handler) (*Server, error) {
+	if _, err := os.Stat(path); err == nil {
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("%s: another instance is listening", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		ln:      ln,
+		path:    path,
+		handler: h,
+	}, nil
+}
+
+func (s *Server) Path() string {
+	return s.path
+}
+
+// accepts connections until Close is called
+func (s *Server) Serve() error {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				s.wg.Wait()
+				return nil
+			}
+			return err
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(c)
+		}()
+	}
+}
+
+// one json request and response per line, until the client hangs up
+func (s *Server) serveConn(c net.Conn) {
+	defer c.Close()
+	dec := json.NewDecoder(c)
+	enc := json.NewEncoder(c)
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			if err != io.EOF {
+				log.Println("control:", err)
+			}
+			return
+		}
+		if err := enc.Encode(s.handler.Handle(req)); err != nil {
+			log.Println("control:", err)
+			return
+		}
+	}
+}
+
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	os.Remove(s.path)
+	return err
+}
+
+// sends one request to the instance listening at path
+func Send(path string, req Request) (Response, error) {
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		return Response{}, err
+	}
+	defer c.Close()
+	if err := json.NewEncoder(c).Encode(req); err != nil {
+		return Response{}, err
+	}
+	var resp Response
+	if err := json.NewDecoder(c).Decode(&resp); err != nil {
+		return Response{}, err
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
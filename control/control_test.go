@@ -0,0 +1,52 @@
+package control
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frame.sock")
+	srv, err := Listen(path, HandlerFunc(func(req Request) Response {
+		switch req.Command {
+		case CmdList:
+			return Response{Sprites: []SpriteInfo{{Index: 0, Width: 2, Height: 3}}}
+		case CmdAddImage:
+			return Response{Message: req.Path}
+		}
+		return Response{Error: "unknown command " + req.Command}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	defer srv.Close()
+
+	resp, err := Send(path, Request{Command: CmdList})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Sprites) != 1 || resp.Sprites[0].Height != 3 {
+		t.Errorf("list = %+v", resp.Sprites)
+	}
+	resp, err = Send(path, Request{Command: CmdAddImage, Path: "/a.png"})
+	if err != nil || resp.Message != "/a.png" {
+		t.Errorf("add-image = %+v, %v", resp, err)
+	}
+	if _, err := Send(path, Request{Command: "nope"}); err == nil {
+		t.Error("expected error for unknown command")
+	}
+}
+
+func TestListen_InUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frame.sock")
+	srv, err := Listen(path, HandlerFunc(func(Request) Response { return Response{} }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	defer srv.Close()
+	if _, err := Listen(path, nil); err == nil {
+		t.Error("expected error listening on a socket in use")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"frame/control"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const ctlUsage = `usage: frame ctl [-socket path] command [args]
+
+commands:
+  add-image [-x x] [-y y] file...   add images, - reads one from stdin
+  list                              list sprites, front to back
+  export [file]                     write the canvas as png, - for stdout
+  clear                             delete all sprites
+`
+
+// runs the `frame ctl` client, returns the exit code
+func ctl(args []string) int {
+	fl := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	fl.Usage = func() { fmt.Fprint(os.Stderr, ctlUsage) }
+	socket := fl.String("socket", control.DefaultSocketPath(), "control socket path")
+	if err := fl.Parse(args); err != nil {
+		return 2
+	}
+	if fl.NArg() == 0 {
+		fl.Usage()
+		return 2
+	}
+	var err error
+	switch cmd, args := fl.Arg(0), fl.Args()[1:]; cmd {
+	case control.CmdAddImage:
+		err = ctlAddImage(*socket, args)
+	case control.CmdList:
+		err = ctlList(*socket)
+	case control.CmdExport:
+		err = ctlExport(*socket, args)
+	case control.CmdClear:
+		var resp control.Response
+		resp, err = control.Send(*socket, control.Request{Command: control.CmdClear})
+		if err == nil {
+			fmt.Println(resp.Message)
+		}
+	default:
+		fl.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "frame ctl:", err)
+		return 1
+	}
+	return 0
+}
+
+func ctlAddImage(socket string, args []string) error {
+	fl := flag.NewFlagSet(control.CmdAddImage, flag.ContinueOnError)
+	x := fl.Int("x", 0, "x position")
+	y := fl.Int("y", 0, "y position")
+	if err := fl.Parse(args); err != nil {
+		return err
+	}
+	if fl.NArg() == 0 {
+		return fmt.Errorf("%s: no files", control.CmdAddImage)
+	}
+	for _, name := range fl.Args() {
+		req := control.Request{Command: control.CmdAddImage, X: *x, Y: *y}
+		if name == "-" {
+			b, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			req.Data = b
+		} else {
+			abs, err := filepath.Abs(name)
+			if err != nil {
+				return err
+			}
+			req.Path = abs
+		}
+		resp, err := control.Send(socket, req)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Printf("%s: %s\n", name, resp.Message)
+	}
+	return nil
+}
+
+func ctlList(socket string) error {
+	resp, err := control.Send(socket, control.Request{Command: control.CmdList})
+	if err != nil {
+		return err
+	}
+	for _, s := range resp.Sprites {
+		fmt.Printf("%d\t%d,%d\t%dx%d\topacity %.2f\n", s.Index, s.X, s.Y, s.Width, s.Height, s.Opacity)
+	}
+	return nil
+}
+
+func ctlExport(socket string, args []string) error {
+	name := "frame.png"
+	if len(args) > 0 {
+		name = args[0]
+	}
+	resp, err := control.Send(socket, control.Request{Command: control.CmdExport})
+	if err != nil {
+		return err
+	}
+	if name == "-" {
+		_, err = os.Stdout.Write(resp.Data)
+		return err
+	}
+	return os.WriteFile(name, resp.Data, 0644)
+}
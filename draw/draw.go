@@ -3,10 +3,12 @@ package draw
 import (
 	"image"
 	"image/color"
+	imagedraw "image/draw"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/colorm"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	xdraw "golang.org/x/image/draw"
 )
 
 func ResizeImage(src *ebiten.Image, size image.Rectangle) *ebiten.Image {
@@ -58,6 +60,38 @@ func CropImage(src *ebiten.Image, r image.Rectangle, offset image.Point) (*ebite
 	return ebiten.NewImageFromImage(im), intr
 }
 
+// copies the pixels of src to a new image.RGBA. the game must be running
+func ToRGBA(src *ebiten.Image) *image.RGBA {
+	img := image.NewRGBA(src.Bounds())
+	src.ReadPixels(img.Pix)
+	return img
+}
+
+// returns the part of img in r, sharing pixels where img supports it
+func SubImage(img image.Image, r image.Rectangle) image.Image {
+	if si, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return si.SubImage(r)
+	}
+	dst := image.NewRGBA(r)
+	imagedraw.Draw(dst, r, img, r.Min, imagedraw.Src)
+	return dst
+}
+
+// scales img to size on the cpu, for images too large to upload as is
+func Scale(img image.Image, size image.Point) *image.RGBA {
+	if size.X < 1 {
+		size.X = 1
+	}
+	if size.Y < 1 {
+		size.Y = 1
+	}
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
 func DrawImage(dst, src *ebiten.Image, pos image.Point, alpha float64) {
 	opts := &colorm.DrawImageOptions{}
 	opts.GeoM.Translate(float64(pos.X), float64(pos.Y))
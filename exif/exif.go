@@ -0,0 +1,163 @@
+// Package exif reads the EXIF tags frame uses from JPEG files
+package exif
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"strings"
+)
+
+const (
+	tagOrientation = 0x0112
+	tagArtist      = 0x013b
+	tagCopyright   = 0x8298
+)
+
+// tags of a jpeg's exif segment. zero values if a tag is missing
+type Info struct {
+	// 1 to 8 as in the exif spec, 1 is upright
+	Orientation int
+	Artist      string
+	Copyright   string
+}
+
+// reads the exif tags of a jpeg. a jpeg without exif gives an empty Info
+func Read(r io.Reader) (Info, error) {
+	br := bufio.NewReader(r)
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil {
+		return Info{}, err
+	}
+	if soi != [2]byte{0xff, 0xd8} {
+		return Info{}, errors.New("exif: not a jpeg")
+	}
+	for {
+		var m [4]byte
+		if _, err := io.ReadFull(br, m[:2]); err != nil {
+			return Info{}, err
+		}
+		if m[0] != 0xff {
+			return Info{}, errors.New("exif: bad marker")
+		}
+		// fill bytes and markers without a length
+		if m[1] == 0xff {
+			br.UnreadByte()
+			continue
+		}
+		if m[1] == 0xd8 || (m[1] >= 0xd0 && m[1] <= 0xd7) {
+			continue
+		}
+		// start of scan, no metadata after it
+		if m[1] == 0xda || m[1] == 0xd9 {
+			return Info{}, nil
+		}
+		if _, err := io.ReadFull(br, m[2:]); err != nil {
+			return Info{}, err
+		}
+		n := int(binary.BigEndian.Uint16(m[2:])) - 2
+		if n < 0 {
+			return Info{}, errors.New("exif: bad segment length")
+		}
+		seg := make([]byte, n)
+		if _, err := io.ReadFull(br, seg); err != nil {
+			return Info{}, err
+		}
+		if m[1] == 0xe1 && len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return parseTIFF(seg[6:])
+		}
+	}
+}
+
+func parseTIFF(b []byte) (Info, error) {
+	if len(b) < 8 {
+		return Info{}, errors.New("exif: short header")
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return Info{}, errors.New("exif: bad byte order")
+	}
+	ifd := int(order.Uint32(b[4:]))
+	if ifd+2 > len(b) {
+		return Info{}, errors.New("exif: bad ifd offset")
+	}
+	info := Info{}
+	n := int(order.Uint16(b[ifd:]))
+	for i := 0; i < n; i++ {
+		e := ifd + 2 + i*12
+		if e+12 > len(b) {
+			break
+		}
+		switch order.Uint16(b[e:]) {
+		case tagOrientation:
+			info.Orientation = int(order.Uint16(b[e+8:]))
+		case tagArtist:
+			info.Artist = ascii(b, e, order)
+		case tagCopyright:
+			info.Copyright = ascii(b, e, order)
+		}
+	}
+	return info, nil
+}
+
+// value of the ascii entry at e. values longer than 4 bytes are stored at
+// an offset
+func ascii(b []byte, e int, order binary.ByteOrder) string {
+	n := int(order.Uint32(b[e+4:]))
+	start := e + 8
+	if n > 4 {
+		start = int(order.Uint32(b[e+8:]))
+	}
+	if n < 0 || start < 0 || start+n > len(b) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(b[start:start+n]), "\x00"))
+}
+
+// returns img turned upright for the exif orientation o. images that are
+// upright or have an unknown orientation are returned as is
+func Orient(img image.Image, o int) image.Image {
+	if o < 2 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+	size := image.Pt(w, h)
+	if o >= 5 {
+		size = image.Pt(h, w)
+	}
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2: // flipped horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // flipped vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // needs rotating 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // needs rotating 90 counterclockwise
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], src.Pix[src.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,103 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// a jpeg with an exif segment holding the orientation, an artist stored
+// inline and a copyright stored at an offset
+func jpegWithExif(t *testing.T, o uint16, order binary.ByteOrder) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	copyright := "(c) Someone\x00"
+	tiff := make([]byte, 8+2+3*12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 3)
+	e := tiff[10:]
+	order.PutUint16(e[0:], tagOrientation)
+	order.PutUint16(e[2:], 3)
+	order.PutUint32(e[4:], 1)
+	order.PutUint16(e[8:], o)
+	e = tiff[22:]
+	order.PutUint16(e[0:], tagArtist)
+	order.PutUint16(e[2:], 2)
+	order.PutUint32(e[4:], 3)
+	copy(e[8:], "Jo\x00")
+	e = tiff[34:]
+	order.PutUint16(e[0:], tagCopyright)
+	order.PutUint16(e[2:], 2)
+	order.PutUint32(e[4:], uint32(len(copyright)))
+	order.PutUint32(e[8:], uint32(len(tiff)))
+	tiff = append(tiff, copyright...)
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(seg)+2))
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	out = append(out, seg...)
+	return append(out, data[2:]...)
+}
+
+func TestRead(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		info, err := Read(bytes.NewReader(jpegWithExif(t, 6, order)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Info{Orientation: 6, Artist: "Jo", Copyright: "(c) Someone"}
+		if info != want {
+			t.Errorf("%v: got %+v, want %+v", order, info, want)
+		}
+	}
+	var plain bytes.Buffer
+	jpeg.Encode(&plain, image.NewGray(image.Rect(0, 0, 1, 1)), nil)
+	info, err := Read(&plain)
+	if err != nil || info.Orientation != 0 {
+		t.Errorf("without exif: %v, %v", info, err)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 3x2, one red pixel at the top left
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	red := color.RGBA{255, 0, 0, 255}
+	img.SetRGBA(0, 0, red)
+	tests := []struct {
+		o    int
+		size image.Point
+		red  image.Point
+	}{
+		{1, image.Pt(3, 2), image.Pt(0, 0)},
+		{2, image.Pt(3, 2), image.Pt(2, 0)},
+		{3, image.Pt(3, 2), image.Pt(2, 1)},
+		{4, image.Pt(3, 2), image.Pt(0, 1)},
+		{5, image.Pt(2, 3), image.Pt(0, 0)},
+		{6, image.Pt(2, 3), image.Pt(1, 0)},
+		{7, image.Pt(2, 3), image.Pt(1, 2)},
+		{8, image.Pt(2, 3), image.Pt(0, 2)},
+	}
+	for _, tt := range tests {
+		got := Orient(img, tt.o)
+		if got.Bounds().Size() != tt.size {
+			t.Errorf("%d: size = %v, want %v", tt.o, got.Bounds().Size(), tt.size)
+			continue
+		}
+		if c := color.RGBAModel.Convert(got.At(tt.red.X, tt.red.Y)); c != red {
+			t.Errorf("%d: at %v = %v, want red", tt.o, tt.red, c)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// a sprite sheet: every sprite packed into one image
+type Atlas struct {
+	Image   string        `json:"image"`
+	Width   int           `json:"width"`
+	Height  int           `json:"height"`
+	Scale   float64       `json:"scale"`
+	Sprites []AtlasSprite `json:"sprites"`
+}
+
+type AtlasSprite struct {
+	Name string `json:"name"`
+	// where the sprite is in the atlas image
+	Frame Rect `json:"frame"`
+	// where the sprite was on the canvas, unscaled
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type Rect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// packs layers into one image with padding pixels around each, and
+// describes where each went. the json is written next to the png
+func WriteAtlas(path string, layers []Layer, padding int, scale float64) error {
+	imgs := make([]*image.RGBA, len(layers))
+	sizes := make([]image.Point, len(layers))
+	for i, l := range layers {
+		img, err := layerPixels(l, l.Rect, scale)
+		if err != nil {
+			return err
+		}
+		imgs[i] = img
+		sizes[i] = imgs[i].Bounds().Size()
+	}
+	rects, size := Pack(sizes, padding)
+	if err := checkSize(size); err != nil {
+		return err
+	}
+	atlas := image.NewRGBA(image.Rectangle{Max: size})
+	meta := Atlas{
+		Image:   filepath.Base(path),
+		Width:   size.X,
+		Height:  size.Y,
+		Scale:   scale,
+		Sprites: []AtlasSprite{},
+	}
+	for i, r := range rects {
+		draw.Draw(atlas, r, imgs[i], image.Point{}, draw.Src)
+		meta.Sprites = append(meta.Sprites, AtlasSprite{
+			Name:  fmt.Sprintf("%02d-%s", i, fileName(layers[i].Name)),
+			Frame: Rect{r.Min.X, r.Min.Y, r.Dx(), r.Dy()},
+			X:     layers[i].Rect.Min.X,
+			Y:     layers[i].Rect.Min.Y,
+		})
+	}
+	if err := writePNG(path, atlas); err != nil {
+		return err
+	}
+	return writeJSON(strings.TrimSuffix(path, filepath.Ext(path))+".json", meta)
+}
+
+// places rectangles of sizes without overlap, padding apart and from the
+// edges, in a roughly square area. uses a skyline, tallest first, each
+// placed where its top edge is lowest
+func Pack(sizes []image.Point, padding int) (rects []image.Rectangle, size image.Point) {
+	rects = make([]image.Rectangle, len(sizes))
+	if len(sizes) == 0 {
+		return rects, image.Point{}
+	}
+	order := make([]int, len(sizes))
+	area, widest := 0, 0
+	for i, s := range sizes {
+		order[i] = i
+		w, h := s.X+padding, s.Y+padding
+		area += w * h
+		if w > widest {
+			widest = w
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		sa, sb := sizes[order[a]], sizes[order[b]]
+		if sa.Y != sb.Y {
+			return sa.Y > sb.Y
+		}
+		return sa.X > sb.X
+	})
+	width := int(math.Ceil(math.Sqrt(float64(area))))
+	if width < widest {
+		width = widest
+	}
+
+	// top edge of the packed area, left to right
+	type segment struct{ x, y, w int }
+	sky := []segment{{0, 0, width}}
+	for _, i := range order {
+		w, h := sizes[i].X+padding, sizes[i].Y+padding
+		best, bestX, bestY := -1, 0, 0
+		for j, seg := range sky {
+			if seg.x+w > width {
+				break
+			}
+			// lowest y the rectangle can sit at starting here
+			y, covered := 0, 0
+			for k := j; k < len(sky) && covered < w; k++ {
+				if sky[k].y > y {
+					y = sky[k].y
+				}
+				covered += sky[k].w
+			}
+			if best == -1 || y < bestY {
+				best, bestX, bestY = j, seg.x, y
+			}
+		}
+		rects[i] = image.Rect(bestX+padding, bestY+padding, bestX+padding+sizes[i].X, bestY+padding+sizes[i].Y)
+		if bestY+h+padding > size.Y {
+			size.Y = bestY + h + padding
+		}
+		if bestX+w+padding > size.X {
+			size.X = bestX + w + padding
+		}
+
+		// raise the skyline under the new rectangle
+		next := []segment{}
+		for _, seg := range sky {
+			end := seg.x + seg.w
+			if end <= bestX || seg.x >= bestX+w {
+				next = append(next, seg)
+				continue
+			}
+			if seg.x < bestX {
+				next = append(next, segment{seg.x, seg.y, bestX - seg.x})
+			}
+			if end > bestX+w {
+				next = append(next, segment{bestX + w, seg.y, end - bestX - w})
+			}
+		}
+		placed := segment{bestX, bestY + h, w}
+		k := sort.Search(len(next), func(k int) bool { return next[k].x > bestX })
+		next = append(next[:k], append([]segment{placed}, next[k:]...)...)
+		sky = sky[:0]
+		for _, seg := range next {
+			if n := len(sky); n > 0 && sky[n-1].y == seg.y {
+				sky[n-1].w += seg.w
+				continue
+			}
+			sky = append(sky, seg)
+		}
+	}
+	return rects, size
+}
@@ -0,0 +1,87 @@
+package export
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPack(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, padding := range []int{0, 2} {
+		sizes := []image.Point{}
+		for i := 0; i < 40; i++ {
+			sizes = append(sizes, image.Pt(1+rnd.Intn(50), 1+rnd.Intn(50)))
+		}
+		rects, size := Pack(sizes, padding)
+		area := 0
+		for i, r := range rects {
+			area += r.Dx() * r.Dy()
+			if r.Size() != sizes[i] {
+				t.Fatalf("rect %d is %v, want size %v", i, r, sizes[i])
+			}
+			if !r.Inset(-padding).In(image.Rectangle{Max: size}) {
+				t.Errorf("rect %d %v with padding outside %v", i, r, size)
+			}
+			for j := 0; j < i; j++ {
+				if r.Inset(-padding).Overlaps(rects[j]) {
+					t.Errorf("rect %d %v within %d of rect %d %v", i, r, padding, j, rects[j])
+				}
+			}
+		}
+		if used := float64(area) / float64(size.X*size.Y); used < 0.5 {
+			t.Errorf("padding %d: only %.0f%% of %v used", padding, used*100, size)
+		}
+	}
+	if rects, size := Pack(nil, 2); len(rects) != 0 || size != (image.Point{}) {
+		t.Errorf("empty pack = %v, %v", rects, size)
+	}
+}
+
+func TestWriteAtlas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sheet.png")
+	layers := []Layer{
+		{Name: "a.png", Image: uniform(4, 2, color.RGBA{255, 0, 0, 255}), Rect: image.Rect(10, 20, 14, 22), Opacity: 1},
+		{Name: "b.png", Image: uniform(2, 6, color.RGBA{0, 255, 0, 255}), Rect: image.Rect(-5, 0, -3, 6), Opacity: 0.2},
+	}
+	if err := WriteAtlas(path, layers, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(filepath.Dir(path), "sheet.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a Atlas
+	if err := json.Unmarshal(b, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Image != "sheet.png" || len(a.Sprites) != 2 {
+		t.Fatalf("atlas = %+v", a)
+	}
+	s := a.Sprites[0]
+	if s.Name != "00-a" || s.X != 10 || s.Y != 20 || s.Frame.W != 4 || s.Frame.H != 2 {
+		t.Errorf("sprite 0 = %+v", s)
+	}
+	if s := a.Sprites[1]; s.X != -5 || s.Frame.W != 2 || s.Frame.H != 6 {
+		t.Errorf("sprite 1 = %+v", s)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriteAtlasTooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sheet.png")
+	layers := []Layer{{Image: uniform(1, 1, color.RGBA{255, 0, 0, 255}), Rect: image.Rect(0, 0, 1, 1), Opacity: 1}}
+	// the padding alone is past the pixel budget
+	if err := WriteAtlas(path, layers, 20000, 1); err == nil {
+		t.Error("oversized atlas written")
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("oversized atlas file created")
+	}
+}
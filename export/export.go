@@ -0,0 +1,227 @@
+// Package export renders sprites to image files without the window, so
+// exports are not limited to what is on screen
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// one sprite to render
+type Layer struct {
+	Name  string
+	Image image.Image
+	// where the image is shown, it is scaled to fit. Image may have more
+	// pixels than Rect, they are used when exporting at a larger scale
+	Rect    image.Rectangle
+	Opacity float64
+}
+
+// smallest rectangle holding every layer
+func Bounds(layers []Layer) image.Rectangle {
+	r := image.Rectangle{}
+	for _, l := range layers {
+		r = r.Union(l.Rect)
+	}
+	return r
+}
+
+// exports larger than this many pixels are refused
+const maxPixels = 100 << 20
+
+// draws layers, back to front, into an image of region r enlarged by scale.
+// only the part of each layer inside r is scaled
+func Compose(layers []Layer, r image.Rectangle, scale float64) (*image.RGBA, error) {
+	size := scaleRect(r.Sub(r.Min), scale).Max
+	if err := checkSize(size); err != nil {
+		return nil, err
+	}
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+	for _, l := range layers {
+		vis := l.Rect.Intersect(r)
+		if l.Opacity <= 0 || vis.Empty() {
+			continue
+		}
+		rect := scaleRect(vis.Sub(r.Min), scale)
+		if rect.Empty() {
+			continue
+		}
+		src, sr := l.Image, sourceRect(l, vis)
+		if rect.Size() != sr.Size() {
+			scaled := image.NewRGBA(image.Rectangle{Max: rect.Size()})
+			xdraw.CatmullRom.Scale(scaled, scaled.Bounds(), src, sr, xdraw.Src, nil)
+			src, sr = scaled, scaled.Bounds()
+		}
+		var mask image.Image
+		if l.Opacity < 1 {
+			mask = image.NewUniform(color.Alpha{uint8(l.Opacity*255 + 0.5)})
+		}
+		draw.DrawMask(dst, rect, src, sr.Min, mask, image.Point{}, draw.Over)
+	}
+	return dst, nil
+}
+
+// the pixels of l.Image shown in vis, a part of l.Rect
+func sourceRect(l Layer, vis image.Rectangle) image.Rectangle {
+	b := l.Image.Bounds()
+	v := vis.Sub(l.Rect.Min)
+	if l.Rect.Size() == b.Size() {
+		return v.Add(b.Min)
+	}
+	fx := float64(b.Dx()) / float64(l.Rect.Dx())
+	fy := float64(b.Dy()) / float64(l.Rect.Dy())
+	return image.Rect(
+		b.Min.X+int(math.Floor(float64(v.Min.X)*fx)),
+		b.Min.Y+int(math.Floor(float64(v.Min.Y)*fy)),
+		b.Min.X+int(math.Ceil(float64(v.Max.X)*fx)),
+		b.Min.Y+int(math.Ceil(float64(v.Max.Y)*fy)),
+	).Intersect(b)
+}
+
+// refuses images too large to allocate safely
+func checkSize(size image.Point) error {
+	if int64(size.X)*int64(size.Y) > maxPixels {
+		return fmt.Errorf("%dx%d pixels is too large to export, the limit is %d megapixels", size.X, size.Y, maxPixels>>20)
+	}
+	return nil
+}
+
+// the pixels of one layer inside r at scale, ignoring its opacity
+func layerPixels(l Layer, r image.Rectangle, scale float64) (*image.RGBA, error) {
+	l.Opacity = 1
+	return Compose([]Layer{l}, r, scale)
+}
+
+func scaleRect(r image.Rectangle, scale float64) image.Rectangle {
+	s := func(v int) int { return int(math.Round(float64(v) * scale)) }
+	return image.Rect(s(r.Min.X), s(r.Min.Y), s(r.Max.X), s(r.Max.Y))
+}
+
+// limits of an export size
+const (
+	maxScale = 32
+	maxWidth = 32768
+)
+
+// how much larger than on the canvas to export
+type Size struct {
+	// a factor, used when Width is zero
+	Scale float64
+	// a target width in pixels
+	Width int
+}
+
+func (s Size) String() string {
+	if s.Width > 0 {
+		return fmt.Sprintf("%dpx", s.Width)
+	}
+	return strconv.FormatFloat(s.Scale, 'f', -1, 64) + "x"
+}
+
+// scale for a region w pixels wide
+func (s Size) Factor(w int) float64 {
+	if s.Width > 0 && w > 0 {
+		return float64(s.Width) / float64(w)
+	}
+	if s.Scale <= 0 {
+		return 1
+	}
+	return s.Scale
+}
+
+// parses "2", "2x" or "1920px"
+func ParseSize(s string) (Size, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if w := strings.TrimSuffix(s, "px"); w != s {
+		n, err := strconv.Atoi(w)
+		if err != nil || n < 1 || n > maxWidth {
+			return Size{}, fmt.Errorf("bad width %q, want 1 to %dpx", s, maxWidth)
+		}
+		return Size{Width: n}, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || !(f > 0) || f > maxScale {
+		return Size{}, fmt.Errorf("bad scale %q, want like 2x, at most %vx, or 1920px", s, maxScale)
+	}
+	return Size{Scale: f}, nil
+}
+
+type Format int
+
+const (
+	PNG Format = iota
+	JPEG
+	GIF
+)
+
+func (f Format) String() string {
+	switch f {
+	case JPEG:
+		return "jpeg"
+	case GIF:
+		return "gif"
+	default:
+		return "png"
+	}
+}
+
+// file extension, with the dot
+func (f Format) Ext() string {
+	if f == JPEG {
+		return ".jpg"
+	}
+	return "." + f.String()
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "png":
+		return PNG, nil
+	case "jpg", "jpeg":
+		return JPEG, nil
+	case "gif":
+		return GIF, nil
+	}
+	return PNG, fmt.Errorf("unknown format %q, want png, jpeg or gif", s)
+}
+
+// format named by the extension of path
+func FormatOf(path string) (Format, error) {
+	return ParseFormat(filepath.Ext(path))
+}
+
+type Options struct {
+	// jpeg quality, 1 to 100
+	Quality int
+}
+
+// encodes img. png keeps transparency, jpeg is drawn over white
+func Encode(w io.Writer, img image.Image, f Format, opts Options) error {
+	switch f {
+	case JPEG:
+		b := img.Bounds()
+		flat := image.NewRGBA(b)
+		draw.Draw(flat, b, image.White, image.Point{}, draw.Src)
+		draw.Draw(flat, b, img, b.Min, draw.Over)
+		q := opts.Quality
+		if q < 1 || q > 100 {
+			q = jpeg.DefaultQuality
+		}
+		return jpeg.Encode(w, flat, &jpeg.Options{Quality: q})
+	case GIF:
+		return gif.Encode(w, img, &gif.Options{NumColors: 256, Drawer: draw.FloydSteinberg})
+	}
+	return png.Encode(w, img)
+}
@@ -0,0 +1,184 @@
+package export
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"testing"
+)
+
+func uniform(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestCompose(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	layers := []Layer{
+		{Image: uniform(4, 4, red), Rect: image.Rect(10, 10, 14, 14), Opacity: 1},
+		// scaled up 2x, half transparent, over the red one
+		{Image: uniform(1, 1, blue), Rect: image.Rect(12, 12, 14, 14), Opacity: 0.5},
+		{Image: uniform(1, 1, blue), Rect: image.Rect(10, 10, 11, 11), Opacity: 0},
+	}
+	r := Bounds(layers)
+	if r != image.Rect(10, 10, 14, 14) {
+		t.Fatalf("Bounds = %v", r)
+	}
+	img, err := Compose(layers, r, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("bounds = %v", img.Bounds())
+	}
+	if got := img.RGBAAt(0, 0); got != red {
+		t.Errorf("hidden layer drawn: %v", got)
+	}
+	got := img.RGBAAt(3, 3)
+	if got.R < 120 || got.R > 135 || got.B < 120 || got.B > 135 || got.A != 255 {
+		t.Errorf("half blue over red = %v", got)
+	}
+
+	// a region of the canvas crops the layers
+	img, err = Compose(layers, image.Rect(13, 13, 20, 20), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Size() != image.Pt(7, 7) || img.RGBAAt(1, 1).A != 0 || img.RGBAAt(0, 0).A != 255 {
+		t.Errorf("region compose wrong: %v %v", img.RGBAAt(0, 0), img.RGBAAt(1, 1))
+	}
+}
+
+func TestEncode(t *testing.T) {
+	img := uniform(2, 2, color.RGBA{0, 0, 0, 0})
+	for _, name := range []string{"a.png", "a.JPG", "a.jpeg", "a.gif"} {
+		f, err := FormatOf(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := Encode(&buf, img, f, Options{Quality: 80}); err != nil {
+			t.Fatal(err)
+		}
+		got, format, err := image.Decode(&buf)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if format != f.String() {
+			t.Errorf("%s: format = %s", name, format)
+		}
+		// jpeg has no alpha, transparent pixels turn white
+		if f == JPEG {
+			if r, _, _, _ := got.At(0, 0).RGBA(); r < 0xf000 {
+				t.Errorf("jpeg pixel = %v, want white", got.At(0, 0))
+			}
+		}
+	}
+	if _, err := FormatOf("a.bmp"); err == nil {
+		t.Error("FormatOf(a.bmp) did not fail")
+	}
+}
+
+func TestComposeScaled(t *testing.T) {
+	// 2x2 on the canvas, with 4x4 source pixels: a red left half
+	src := uniform(4, 4, color.RGBA{0, 0, 255, 255})
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 2; x++ {
+			src.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+	layers := []Layer{{Image: src, Rect: image.Rect(5, 5, 7, 7), Opacity: 1}}
+	img, err := Compose(layers, image.Rect(4, 4, 7, 7), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Size() != image.Pt(6, 6) {
+		t.Fatalf("size = %v", img.Bounds().Size())
+	}
+	if got := img.RGBAAt(2, 2); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("at 2,2 = %v, want red", got)
+	}
+	if got := img.RGBAAt(5, 5); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("at 5,5 = %v, want blue", got)
+	}
+	if got := img.RGBAAt(1, 1); got.A != 0 {
+		t.Errorf("at 1,1 = %v, want transparent", got)
+	}
+}
+
+func TestComposeCrops(t *testing.T) {
+	// a 4000 pixel wide layer mostly outside the region is only scaled
+	// where it shows
+	wide := uniform(4000, 10, color.RGBA{0, 255, 0, 255})
+	wide.SetRGBA(3000, 0, color.RGBA{255, 0, 0, 255})
+	layers := []Layer{{Image: wide, Rect: image.Rect(0, 0, 4000, 10), Opacity: 1}}
+	img, err := Compose(layers, image.Rect(3000, 0, 3002, 2), 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Size() != image.Pt(60, 60) {
+		t.Fatalf("size = %v", img.Bounds().Size())
+	}
+	if got := img.RGBAAt(5, 5); got.R < 200 || got.G > 50 {
+		t.Errorf("at 5,5 = %v, want red", got)
+	}
+	if got := img.RGBAAt(55, 55); got.G < 240 || got.R > 20 {
+		t.Errorf("at 55,55 = %v, want green", got)
+	}
+
+	// a layer with more pixels than it shows is cropped in its own pixels
+	big := uniform(40, 40, color.RGBA{0, 0, 255, 255})
+	for y := 0; y < 40; y++ {
+		for x := 20; x < 40; x++ {
+			big.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+	layers = []Layer{{Image: big, Rect: image.Rect(0, 0, 4, 4), Opacity: 1}}
+	if img, err = Compose(layers, image.Rect(2, 0, 4, 4), 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("right half = %v, want red", got)
+	}
+
+	if _, err := Compose(layers, image.Rect(0, 0, 4000, 4000), 8); err == nil {
+		t.Error("a 32000 pixel square export did not fail")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   Size
+		factor float64
+	}{
+		{"2", Size{Scale: 2}, 2},
+		{"4x", Size{Scale: 4}, 4},
+		{" 1.5X ", Size{Scale: 1.5}, 1.5},
+		{"1600px", Size{Width: 1600}, 2},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		if f := got.Factor(800); f != tt.factor {
+			t.Errorf("%q: Factor(800) = %v, want %v", tt.in, f, tt.factor)
+		}
+	}
+	for _, in := range []string{"", "0", "-2x", "wide", "0px", "1000x", "100000px"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) did not fail", in)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"path"
+	"strconv"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	oraMimetype  = "image/openraster"
+	oraThumbSize = 256
+)
+
+type oraImage struct {
+	XMLName xml.Name   `xml:"image"`
+	Version string     `xml:"version,attr"`
+	W       int        `xml:"w,attr"`
+	H       int        `xml:"h,attr"`
+	Layers  []oraLayer `xml:"stack>layer"`
+}
+
+type oraLayer struct {
+	Name       string  `xml:"name,attr"`
+	Src        string  `xml:"src,attr"`
+	X          int     `xml:"x,attr"`
+	Y          int     `xml:"y,attr"`
+	Opacity    float64 `xml:"opacity,attr"`
+	Visibility string  `xml:"visibility,attr"`
+}
+
+// writes layers, back to front, as an OpenRaster file of region r enlarged
+// by scale. each layer is a png at its offset, with its opacity
+func WriteORA(w io.Writer, layers []Layer, r image.Rectangle, scale float64) error {
+	merged, err := Compose(layers, r, scale)
+	if err != nil {
+		return err
+	}
+	stack := oraImage{
+		Version: "0.0.3",
+		W:       merged.Bounds().Dx(),
+		H:       merged.Bounds().Dy(),
+	}
+	z := zip.NewWriter(w)
+	// the mimetype comes first and uncompressed, so the format can be sniffed
+	f, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, oraMimetype); err != nil {
+		return err
+	}
+	// the stack lists the top layer first
+	for i := len(layers) - 1; i >= 0; i-- {
+		l := layers[i]
+		img, err := layerPixels(l, l.Rect, scale)
+		if err != nil {
+			return err
+		}
+		src := fmt.Sprintf("data/%03d.png", i)
+		if err := writeZipPNG(z, src, img); err != nil {
+			return err
+		}
+		pos := scaleRect(l.Rect.Sub(r.Min), scale).Min
+		stack.Layers = append(stack.Layers, oraLayer{
+			Name:       l.Name,
+			Src:        src,
+			X:          pos.X,
+			Y:          pos.Y,
+			Opacity:    l.Opacity,
+			Visibility: "visible",
+		})
+	}
+	if err := writeZipPNG(z, "mergedimage.png", merged); err != nil {
+		return err
+	}
+	if err := writeZipPNG(z, "Thumbnails/thumbnail.png", thumbnail(merged, oraThumbSize)); err != nil {
+		return err
+	}
+	f, err = z.Create("stack.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, xml.Header); err != nil {
+		return err
+	}
+	if err := xml.NewEncoder(f).Encode(stack); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// reports whether data is an OpenRaster file
+func IsORA(data []byte) bool {
+	// a zip whose first entry, stored, is the mimetype. its local header
+	// has the name and extra field lengths at 26 and 28, then the name, the
+	// extra field and the data
+	if len(data) < 30 || !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return false
+	}
+	name := 30 + int(binary.LittleEndian.Uint16(data[26:]))
+	start := name + int(binary.LittleEndian.Uint16(data[28:]))
+	return len(data) >= start+len(oraMimetype) &&
+		string(data[30:name]) == "mimetype" &&
+		string(data[start:start+len(oraMimetype)]) == oraMimetype
+}
+
+// reads the layers of an OpenRaster file, back to front, and the size of
+// its canvas. hidden layers have zero opacity
+func ReadORA(data []byte) ([]Layer, image.Point, error) {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	files := map[string]*zip.File{}
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+	sf, ok := files["stack.xml"]
+	if !ok {
+		return nil, image.Point{}, errors.New("ora: no stack.xml")
+	}
+	rc, err := sf.Open()
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	layers, size, err := readStack(rc)
+	rc.Close()
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	out := make([]Layer, 0, len(layers))
+	for i := len(layers) - 1; i >= 0; i-- {
+		l := layers[i]
+		f, ok := files[path.Clean(l.Src)]
+		if !ok {
+			return nil, image.Point{}, fmt.Errorf("ora: missing layer %q", l.Src)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, image.Point{}, err
+		}
+		img, err := png.Decode(rc)
+		rc.Close()
+		if err != nil {
+			return nil, image.Point{}, fmt.Errorf("ora: layer %q: %w", l.Src, err)
+		}
+		opacity := l.Opacity
+		if l.Visibility == "hidden" {
+			opacity = 0
+		}
+		b := img.Bounds()
+		out = append(out, Layer{
+			Name:    l.Name,
+			Image:   img,
+			Rect:    image.Rect(l.X, l.Y, l.X+b.Dx(), l.Y+b.Dy()),
+			Opacity: opacity,
+		})
+	}
+	return out, size, nil
+}
+
+// layers of stack.xml top first, nested stacks flattened in document order
+// with their offsets added
+func readStack(r io.Reader) ([]oraLayer, image.Point, error) {
+	d := xml.NewDecoder(r)
+	layers := []oraLayer{}
+	size := image.Point{}
+	offsets := []image.Point{{}}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, image.Point{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			attrs := map[string]string{}
+			for _, a := range t.Attr {
+				attrs[a.Name.Local] = a.Value
+			}
+			atoi := func(k string) int {
+				n, _ := strconv.Atoi(attrs[k])
+				return n
+			}
+			off := offsets[len(offsets)-1]
+			switch t.Name.Local {
+			case "image":
+				size = image.Pt(atoi("w"), atoi("h"))
+			case "stack":
+				offsets = append(offsets, off.Add(image.Pt(atoi("x"), atoi("y"))))
+			case "layer":
+				opacity := 1.0
+				if v, ok := attrs["opacity"]; ok {
+					opacity, _ = strconv.ParseFloat(v, 64)
+				}
+				layers = append(layers, oraLayer{
+					Name:       attrs["name"],
+					Src:        attrs["src"],
+					X:          off.X + atoi("x"),
+					Y:          off.Y + atoi("y"),
+					Opacity:    opacity,
+					Visibility: attrs["visibility"],
+				})
+			}
+		case xml.EndElement:
+			if t.Name.Local == "stack" && len(offsets) > 1 {
+				offsets = offsets[:len(offsets)-1]
+			}
+		}
+	}
+	return layers, size, nil
+}
+
+func writeZipPNG(z *zip.Writer, name string, img image.Image) error {
+	f, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	return png.Encode(f, img)
+}
+
+// img scaled down to fit within max pixels square
+func thumbnail(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= max && b.Dy() <= max {
+		return img
+	}
+	w, h := max, b.Dy()*max/b.Dx()
+	if b.Dy() > b.Dx() {
+		w, h = b.Dx()*max/b.Dy(), max
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	return dst
+}
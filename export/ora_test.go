@@ -0,0 +1,102 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+)
+
+func TestORARoundTrip(t *testing.T) {
+	layers := []Layer{
+		{Name: "back", Image: uniform(4, 4, color.RGBA{255, 0, 0, 255}), Rect: image.Rect(10, 10, 14, 14), Opacity: 1},
+		{Name: "front", Image: uniform(2, 3, color.RGBA{0, 0, 255, 255}), Rect: image.Rect(12, 11, 14, 14), Opacity: 0.25},
+	}
+	var buf bytes.Buffer
+	if err := WriteORA(&buf, layers, image.Rect(0, 0, 20, 30), 1); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if !IsORA(data) {
+		t.Fatal("IsORA = false")
+	}
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, f := range z.File {
+		names[f.Name] = true
+	}
+	for _, n := range []string{"stack.xml", "mergedimage.png", "Thumbnails/thumbnail.png"} {
+		if !names[n] {
+			t.Errorf("missing %s", n)
+		}
+	}
+
+	got, size, err := ReadORA(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != image.Pt(20, 30) {
+		t.Errorf("size = %v", size)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d layers", len(got))
+	}
+	for i, l := range got {
+		if l.Name != layers[i].Name || l.Rect != layers[i].Rect || l.Opacity != layers[i].Opacity {
+			t.Errorf("layer %d = %v %v %v, want %v %v %v", i, l.Name, l.Rect, l.Opacity, layers[i].Name, layers[i].Rect, layers[i].Opacity)
+		}
+	}
+	// pixels are written at full opacity
+	if _, _, _, a := got[1].Image.At(0, 0).RGBA(); a != 0xffff {
+		t.Errorf("alpha = %x", a)
+	}
+}
+
+func TestReadStack(t *testing.T) {
+	xml := `<image w="100" h="50"><stack>
+		<layer name="top" src="data/a.png" x="1" y="2"/>
+		<stack x="10" y="20"><layer name="nested" src="data/b.png" x="1" y="1" opacity="0.5" visibility="hidden"/></stack>
+		<layer name="bottom" src="data/c.png"/>
+	</stack></image>`
+	layers, size, err := readStack(io.Reader(bytes.NewBufferString(xml)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != image.Pt(100, 50) || len(layers) != 3 {
+		t.Fatalf("size %v, layers %v", size, layers)
+	}
+	if l := layers[1]; l.Name != "nested" || l.X != 11 || l.Y != 21 || l.Opacity != 0.5 || l.Visibility != "hidden" {
+		t.Errorf("nested = %+v", l)
+	}
+	if l := layers[2]; l.X != 0 || l.Opacity != 1 {
+		t.Errorf("bottom = %+v", l)
+	}
+}
+
+func TestIsORAExtraField(t *testing.T) {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	w, err := z.CreateHeader(&zip.FileHeader{
+		Name:   "mimetype",
+		Method: zip.Store,
+		Extra:  []byte{0xfe, 0xca, 4, 0, 1, 2, 3, 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, oraMimetype)
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !IsORA(buf.Bytes()) {
+		t.Error("IsORA = false with an extra field")
+	}
+	if IsORA(buf.Bytes()[:40]) {
+		t.Error("IsORA = true for a truncated file")
+	}
+}
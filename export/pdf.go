@@ -0,0 +1,234 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// a pdf page and the resolution of the images on it
+type Page struct {
+	// a known size like a4, or "fit" for a page the size of the export
+	Name string
+	// in points, zero for fit
+	Width, Height float64
+	// image pixels per inch
+	DPI float64
+}
+
+// page sizes in points, portrait
+var pageSizes = map[string][2]float64{
+	"a3":      {841.89, 1190.55},
+	"a4":      {595.28, 841.89},
+	"a5":      {419.53, 595.28},
+	"letter":  {612, 792},
+	"legal":   {612, 1008},
+	"tabloid": {792, 1224},
+}
+
+func (p Page) String() string {
+	return fmt.Sprintf("%s %sdpi", p.Name, strconv.FormatFloat(p.DPI, 'f', -1, 64))
+}
+
+// parses a page like "a4 300dpi", "letter" or "fit 150dpi". the dpi
+// defaults to 300
+func ParsePage(s string) (Page, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 || len(fields) > 2 {
+		return Page{}, fmt.Errorf("bad page %q, want like a4 300dpi", s)
+	}
+	p := Page{Name: fields[0], DPI: 300}
+	if size, ok := pageSizes[p.Name]; ok {
+		p.Width, p.Height = size[0], size[1]
+	} else if p.Name != "fit" {
+		return Page{}, fmt.Errorf("unknown page size %q, want fit, a3, a4, a5, letter, legal or tabloid", fields[0])
+	}
+	if len(fields) == 2 {
+		dpi, err := strconv.ParseFloat(strings.TrimSuffix(fields[1], "dpi"), 64)
+		if err != nil || !(dpi > 0) || math.IsInf(dpi, 0) {
+			return Page{}, fmt.Errorf("bad dpi %q", fields[1])
+		}
+		p.DPI = dpi
+	}
+	return p, nil
+}
+
+// writes layers, back to front, as a one page pdf of region r. on a fit
+// page one canvas pixel is scale image pixels at the page's dpi. on a
+// sized page the region is centered as large as it fits, turning the page
+// for wide regions, and images get the dpi's pixels
+func WritePDF(w io.Writer, layers []Layer, r image.Rectangle, page Page, scale float64) error {
+	if r.Empty() {
+		return errors.New("pdf: empty region")
+	}
+	pw, ph, ox, oy, k := page.layout(r, scale)
+	scale = page.Scale(r, scale)
+	// every layer is cropped to r, so none is larger than the region
+	if err := checkSize(scaleRect(r.Sub(r.Min), scale).Max); err != nil {
+		return err
+	}
+
+	p := &pdfWriter{}
+	p.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	catalog, pages, pageID := p.reserve(), p.reserve(), p.reserve()
+
+	var content bytes.Buffer
+	images, states := []string{}, []string{}
+	fmt.Fprintf(&content, "q %s %s %s %s re W n\n", num(ox), num(oy), num(float64(r.Dx())*k), num(float64(r.Dy())*k))
+	for _, l := range layers {
+		vis := l.Rect.Intersect(r)
+		if l.Opacity <= 0 || vis.Empty() {
+			continue
+		}
+		rgba, err := layerPixels(l, vis, scale)
+		if err != nil {
+			return err
+		}
+		if rgba.Bounds().Empty() {
+			continue
+		}
+		img := image.NewNRGBA(rgba.Bounds())
+		draw.Draw(img, img.Bounds(), rgba, image.Point{}, draw.Src)
+		id, err := p.image(img)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("Im%d", len(images))
+		images = append(images, fmt.Sprintf("/%s %d 0 R", name, id))
+		at := vis.Sub(r.Min)
+		x := ox + float64(at.Min.X)*k
+		y := oy + float64(r.Dy()-at.Max.Y)*k
+		content.WriteString("q ")
+		if l.Opacity < 1 {
+			gs := fmt.Sprintf("GS%d", len(states))
+			a := num(clamp01(l.Opacity))
+			states = append(states, fmt.Sprintf("/%s << /ca %s /CA %s >>", gs, a, a))
+			fmt.Fprintf(&content, "/%s gs ", gs)
+		}
+		fmt.Fprintf(&content, "%s 0 0 %s %s %s cm /%s Do Q\n", num(float64(at.Dx())*k), num(float64(at.Dy())*k), num(x), num(y), name)
+	}
+	content.WriteString("Q\n")
+
+	contentID, err := p.stream("", content.Bytes())
+	if err != nil {
+		return err
+	}
+	p.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /XObject << %s >> /ExtGState << %s >> >> /Contents %d 0 R >>",
+		pages, num(pw), num(ph), strings.Join(images, " "), strings.Join(states, " "), contentID))
+	p.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%d 0 R] /Count 1 >>", pageID))
+	p.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	info := p.reserve()
+	p.object(info, "<< /Producer (frame) >>")
+	p.finish(catalog, info)
+	_, err = w.Write(p.buf.Bytes())
+	return err
+}
+
+// image pixels per canvas pixel when region r is put on the page. scale
+// for a fit page, set by the dpi for a sized page
+func (p Page) Scale(r image.Rectangle, scale float64) float64 {
+	if p.Width <= 0 {
+		return scale
+	}
+	_, _, _, _, k := p.layout(r, scale)
+	return k * p.DPI / 72
+}
+
+// the page size, where region r goes on it and the points per canvas pixel
+func (p Page) layout(r image.Rectangle, scale float64) (w, h, x, y, k float64) {
+	k = scale * 72 / p.DPI
+	if p.Width <= 0 {
+		return float64(r.Dx()) * k, float64(r.Dy()) * k, 0, 0, k
+	}
+	w, h = p.Width, p.Height
+	if (r.Dx() > r.Dy()) != (w > h) {
+		w, h = h, w
+	}
+	k = math.Min(w/float64(r.Dx()), h/float64(r.Dy()))
+	return w, h, (w - float64(r.Dx())*k) / 2, (h - float64(r.Dy())*k) / 2, k
+}
+
+// a pdf file built in memory. object ids start at 1
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// an id for an object written later
+func (p *pdfWriter) reserve() int {
+	p.offsets = append(p.offsets, 0)
+	return len(p.offsets)
+}
+
+func (p *pdfWriter) object(id int, body string) {
+	p.offsets[id-1] = p.buf.Len()
+	fmt.Fprintf(&p.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// writes data compressed as a stream object, dict holds the entries besides
+// its length and filter
+func (p *pdfWriter) stream(dict string, data []byte) (int, error) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	if _, err := zw.Write(data); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	id := p.reserve()
+	p.offsets[id-1] = p.buf.Len()
+	fmt.Fprintf(&p.buf, "%d 0 obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", id, dict, z.Len())
+	p.buf.Write(z.Bytes())
+	p.buf.WriteString("\nendstream\nendobj\n")
+	return id, nil
+}
+
+// an rgb image object, with a soft mask when it is not opaque
+func (p *pdfWriter) image(img *image.NRGBA) (int, error) {
+	b := img.Bounds()
+	rgb := make([]byte, 0, b.Dx()*b.Dy()*3)
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	opaque := true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			px := row[x*4 : x*4+4]
+			rgb = append(rgb, px[0], px[1], px[2])
+			alpha = append(alpha, px[3])
+			opaque = opaque && px[3] == 0xff
+		}
+	}
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8", b.Dx(), b.Dy())
+	mask := ""
+	if !opaque {
+		id, err := p.stream(dict+" /ColorSpace /DeviceGray", alpha)
+		if err != nil {
+			return 0, err
+		}
+		mask = fmt.Sprintf(" /SMask %d 0 R", id)
+	}
+	return p.stream(dict+" /ColorSpace /DeviceRGB"+mask, rgb)
+}
+
+// writes the cross reference table and trailer
+func (p *pdfWriter) finish(root, info int) {
+	xref := p.buf.Len()
+	fmt.Fprintf(&p.buf, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, off := range p.offsets {
+		fmt.Fprintf(&p.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&p.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, root, info, xref)
+}
+
+// a number with at most 3 decimals
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
@@ -0,0 +1,109 @@
+package export
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestParsePage(t *testing.T) {
+	for s, want := range map[string]Page{
+		"a4":          {"a4", 595.28, 841.89, 300},
+		"Letter 72":   {"letter", 612, 792, 72},
+		"fit 150dpi":  {"fit", 0, 0, 150},
+		" a3  600dpi": {"a3", 841.89, 1190.55, 600},
+	} {
+		got, err := ParsePage(s)
+		if err != nil || got != want {
+			t.Errorf("ParsePage(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "b9", "a4 0dpi", "a4 x", "a4 300 dpi"} {
+		if _, err := ParsePage(s); err == nil {
+			t.Errorf("ParsePage(%q) succeeded", s)
+		}
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	layers := []Layer{
+		{Name: "back", Image: uniform(40, 20, color.RGBA{255, 0, 0, 255}), Rect: image.Rect(0, 0, 40, 20), Opacity: 1},
+		{Name: "front", Image: uniform(10, 10, color.RGBA{0, 0, 128, 128}), Rect: image.Rect(30, 10, 40, 20), Opacity: 0.5},
+		{Name: "hidden", Image: uniform(10, 10, color.RGBA{0, 0, 255, 255}), Rect: image.Rect(0, 0, 10, 10), Opacity: 0},
+	}
+	r := image.Rect(0, 0, 40, 20)
+	for _, tc := range []struct {
+		page  Page
+		scale float64
+		box   string
+		width int
+	}{
+		// 40x20 pixels at 2x and 144dpi is 40x20 points
+		{Page{Name: "fit", DPI: 144}, 2, "[0 0 40 20]", 80},
+		// a wide region turns the page, 841.89 points are 11.69 inches
+		{Page{Name: "a4", Width: 595.28, Height: 841.89, DPI: 72}, 1, "[0 0 841.89 595.28]", 842},
+	} {
+		var buf bytes.Buffer
+		if err := WritePDF(&buf, layers, r, tc.page, tc.scale); err != nil {
+			t.Fatal(err)
+		}
+		pdf := buf.Bytes()
+		if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+			t.Fatal("not a pdf")
+		}
+		if !bytes.Contains(pdf, []byte("/MediaBox "+tc.box)) {
+			t.Errorf("%v: no MediaBox %s", tc.page, tc.box)
+		}
+		// two images, one with a soft mask, and one opacity state
+		rgb := regexp.MustCompile(`/DeviceRGB[^>]*`).FindAll(pdf, -1)
+		if len(rgb) != 2 || bytes.Count(pdf, []byte("/SMask")) != 1 || !bytes.Contains(pdf, []byte("/ca 0.5")) {
+			t.Errorf("%v: images %q", tc.page, rgb)
+		}
+		// the first image is the back layer
+		m := regexp.MustCompile(`/Width (\d+)`).FindSubmatch(pdf)
+		if w, _ := strconv.Atoi(string(m[1])); w != tc.width {
+			t.Errorf("%v: image width %d, want %d", tc.page, w, tc.width)
+		}
+		checkXref(t, pdf)
+	}
+}
+
+func TestWritePDFCrops(t *testing.T) {
+	layers := []Layer{{Image: uniform(4000, 4000, color.RGBA{255, 0, 0, 255}), Rect: image.Rect(0, 0, 4000, 4000), Opacity: 1}}
+	a4 := Page{Name: "a4", Width: 595.28, Height: 841.89, DPI: 300}
+	// a small region fills the page, only its part of the layer is scaled
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, layers, image.Rect(100, 100, 110, 105), a4, 1); err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`/Width (\d+)`).FindSubmatch(buf.Bytes())
+	if w, _ := strconv.Atoi(string(m[1])); w < 3000 || w > 3600 {
+		t.Errorf("image width %d, want about 3508", w)
+	}
+	checkXref(t, buf.Bytes())
+	// the whole layer at 3000dpi is past the pixel budget
+	a4.DPI = 3000
+	if err := WritePDF(&buf, layers, image.Rect(0, 0, 4000, 4000), a4, 1); err == nil {
+		t.Error("oversized pdf written")
+	}
+}
+
+// every object in the cross reference table starts where it says
+func checkXref(t *testing.T, pdf []byte) {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(pdf)
+	start, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(pdf[start:], []byte("xref\n")) {
+		t.Fatal("startxref does not point at the table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[start:], -1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := strconv.Itoa(i+1) + " 0 obj"; !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("object %d is not at %d", i+1, off)
+		}
+	}
+}
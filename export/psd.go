@@ -0,0 +1,155 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"unicode/utf16"
+)
+
+// photoshop's limit for psd files
+const psdMaxSize = 30000
+
+// writes layers, back to front, as a photoshop file of region r enlarged by
+// scale. each layer is a raster layer at its offset, with its opacity
+func WritePSD(w io.Writer, layers []Layer, r image.Rectangle, scale float64) error {
+	merged, err := Compose(layers, r, scale)
+	if err != nil {
+		return err
+	}
+	size := merged.Bounds().Size()
+	if size.X < 1 || size.Y < 1 || size.X > psdMaxSize || size.Y > psdMaxSize {
+		return errors.New("psd: canvas must be 1 to 30000 pixels wide and high")
+	}
+	var buf bytes.Buffer
+	// header: rgb, 8 bits per channel
+	buf.WriteString("8BPS")
+	putBE(&buf, uint16(1), [6]byte{}, uint16(3), uint32(size.Y), uint32(size.X), uint16(8), uint16(3))
+	// no color mode data or image resources
+	putBE(&buf, uint32(0), uint32(0))
+
+	info, err := psdLayerInfo(layers, r, scale)
+	if err != nil {
+		return err
+	}
+	// layer and mask information, without a global mask
+	putBE(&buf, uint32(4+len(info)+4), uint32(len(info)))
+	buf.Write(info)
+	putBE(&buf, uint32(0))
+
+	// the merged image for programs that do not read layers, over white
+	flat := image.NewNRGBA(merged.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), merged, image.Point{}, draw.Over)
+	putBE(&buf, uint16(0))
+	for c := 0; c < 3; c++ {
+		buf.Write(psdChannel(flat, c))
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// layer records, bottom first, then their channels
+func psdLayerInfo(layers []Layer, r image.Rectangle, scale float64) ([]byte, error) {
+	if len(layers) > 1<<15-1 {
+		return nil, errors.New("psd: too many layers")
+	}
+	var records, channels bytes.Buffer
+	putBE(&records, int16(len(layers)))
+	for _, l := range layers {
+		rgba, err := layerPixels(l, l.Rect, scale)
+		if err != nil {
+			return nil, err
+		}
+		img := image.NewNRGBA(rgba.Bounds())
+		draw.Draw(img, img.Bounds(), rgba, image.Point{}, draw.Src)
+		rect := img.Bounds().Add(scaleRect(l.Rect.Sub(r.Min), scale).Min)
+		putBE(&records, int32(rect.Min.Y), int32(rect.Min.X), int32(rect.Max.Y), int32(rect.Max.X))
+		// alpha, red, green, blue
+		putBE(&records, uint16(4))
+		for _, id := range []int16{-1, 0, 1, 2} {
+			c := int(id)
+			if id < 0 {
+				c = 3
+			}
+			data := psdChannel(img, c)
+			putBE(&records, id, uint32(2+len(data)))
+			putBE(&channels, uint16(0))
+			channels.Write(data)
+		}
+		opacity := uint8(clamp01(l.Opacity)*255 + 0.5)
+		records.WriteString("8BIMnorm")
+		putBE(&records, opacity, uint8(0), uint8(0), uint8(0))
+		extra := psdLayerExtra(l.Name)
+		putBE(&records, uint32(len(extra)))
+		records.Write(extra)
+	}
+	info := append(records.Bytes(), channels.Bytes()...)
+	if len(info)%2 != 0 {
+		info = append(info, 0)
+	}
+	return info, nil
+}
+
+// no mask or blending ranges, the name as a pascal string padded to four
+// bytes and as utf-16 for names that are not ascii
+func psdLayerExtra(name string) []byte {
+	var buf bytes.Buffer
+	putBE(&buf, uint32(0), uint32(0))
+	ascii := []byte{}
+	for _, c := range name {
+		if c >= 0x80 || c < 0x20 {
+			c = '_'
+		}
+		if len(ascii) < 255 {
+			ascii = append(ascii, byte(c))
+		}
+	}
+	pascal := append([]byte{byte(len(ascii))}, ascii...)
+	for len(pascal)%4 != 0 {
+		pascal = append(pascal, 0)
+	}
+	buf.Write(pascal)
+	u := utf16.Encode([]rune(name))
+	buf.WriteString("8BIMluni")
+	length := 4 + 2*len(u)
+	pad := length % 4
+	if pad != 0 {
+		pad = 4 - pad
+	}
+	putBE(&buf, uint32(length+pad), uint32(len(u)), u, make([]byte, pad))
+	return buf.Bytes()
+}
+
+// one channel of img, 0 to 3 for red, green, blue and alpha, row by row
+func psdChannel(img *image.NRGBA, c int) []byte {
+	b := img.Bounds()
+	data := make([]byte, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			data = append(data, row[x*4+c])
+		}
+	}
+	return data
+}
+
+// writes each value big endian
+func putBE(buf *bytes.Buffer, v ...interface{}) {
+	for _, x := range v {
+		_ = binary.Write(buf, binary.BigEndian, x)
+	}
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
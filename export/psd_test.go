@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestWritePSD(t *testing.T) {
+	layers := []Layer{
+		{Name: "back", Image: uniform(4, 2, color.RGBA{255, 0, 0, 255}), Rect: image.Rect(1, 1, 5, 3), Opacity: 1},
+		{Name: "fröntal", Image: uniform(2, 3, color.RGBA{0, 0, 255, 255}), Rect: image.Rect(-1, 2, 1, 5), Opacity: 0.5},
+	}
+	var buf bytes.Buffer
+	if err := WritePSD(&buf, layers, image.Rect(0, 0, 8, 6), 1); err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(buf.Bytes())
+	read := func(v interface{}) {
+		if err := binary.Read(r, binary.BigEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var header struct {
+		Sig      [4]byte
+		Version  uint16
+		_        [6]byte
+		Channels uint16
+		H, W     uint32
+		Depth    uint16
+		Mode     uint16
+		ModeData uint32
+		Res      uint32
+		Section  uint32
+		Info     uint32
+		Count    int16
+	}
+	read(&header)
+	if string(header.Sig[:]) != "8BPS" || header.W != 8 || header.H != 6 || header.Count != 2 {
+		t.Fatalf("header = %+v", header)
+	}
+	if header.Info%2 != 0 || header.Section != header.Info+8 {
+		t.Errorf("section %d, info %d", header.Section, header.Info)
+	}
+	want := []struct {
+		rect    image.Rectangle
+		opacity uint8
+		name    string
+	}{
+		{image.Rect(1, 1, 5, 3), 255, "back"},
+		{image.Rect(-1, 2, 1, 5), 128, "fr_ntal"},
+	}
+	for i, w := range want {
+		var rec struct {
+			Top, Left, Bottom, Right int32
+			Channels                 uint16
+		}
+		read(&rec)
+		rect := image.Rect(int(rec.Left), int(rec.Top), int(rec.Right), int(rec.Bottom))
+		if rect != w.rect || rec.Channels != 4 {
+			t.Errorf("layer %d: rect %v, %d channels", i, rect, rec.Channels)
+		}
+		for c := 0; c < 4; c++ {
+			var ch struct {
+				ID  int16
+				Len uint32
+			}
+			read(&ch)
+			if int(ch.Len) != 2+rect.Dx()*rect.Dy() {
+				t.Errorf("layer %d channel %d: length %d", i, ch.ID, ch.Len)
+			}
+		}
+		var blend struct {
+			Sig, Mode               [4]byte
+			Opacity, Clip, Flags, _ uint8
+			Extra                   uint32
+		}
+		read(&blend)
+		if string(blend.Sig[:])+string(blend.Mode[:]) != "8BIMnorm" || blend.Opacity != w.opacity {
+			t.Errorf("layer %d: blend %+v", i, blend)
+		}
+		extra := make([]byte, blend.Extra)
+		read(extra)
+		name := string(extra[9 : 9+extra[8]])
+		if name != w.name {
+			t.Errorf("layer %d: name %q, want %q", i, name, w.name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// where each exported sprite goes to reassemble the collage. positions and
+// sizes are in exported pixels
+type SpriteSheet struct {
+	Scale   float64       `json:"scale"`
+	Sprites []SpriteEntry `json:"sprites"`
+}
+
+type SpriteEntry struct {
+	File string `json:"file"`
+	Name string `json:"name"`
+	// 0 is the back most sprite
+	Z       int     `json:"z"`
+	X       int     `json:"x"`
+	Y       int     `json:"y"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Opacity float64 `json:"opacity"`
+}
+
+// writes each layer to its own png in dir, named from its z order and name,
+// and sprites.json describing them. opacity is left to the json
+func WriteSprites(dir string, layers []Layer, scale float64) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	sheet := SpriteSheet{Scale: scale, Sprites: []SpriteEntry{}}
+	for z, l := range layers {
+		img, err := layerPixels(l, l.Rect, scale)
+		if err != nil {
+			return err
+		}
+		file := fmt.Sprintf("%02d-%s.png", z, fileName(l.Name))
+		if err := writePNG(filepath.Join(dir, file), img); err != nil {
+			return err
+		}
+		sheet.Sprites = append(sheet.Sprites, SpriteEntry{
+			File:    file,
+			Name:    l.Name,
+			Z:       z,
+			X:       int(math.Round(float64(l.Rect.Min.X) * scale)),
+			Y:       int(math.Round(float64(l.Rect.Min.Y) * scale)),
+			Width:   img.Bounds().Dx(),
+			Height:  img.Bounds().Dy(),
+			Opacity: l.Opacity,
+		})
+	}
+	return writeJSON(filepath.Join(dir, "sprites.json"), sheet)
+}
+
+// name without its extension, reduced to characters safe in file names
+func fileName(name string) string {
+	name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+	s = strings.Trim(s, "-")
+	if s == "" || s == "." {
+		return "sprite"
+	}
+	return s
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Encode(f, img, PNG, Options{}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeJSON(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
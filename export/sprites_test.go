@@ -0,0 +1,55 @@
+package export
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSprites(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	layers := []Layer{
+		{Name: "back.png", Image: uniform(2, 2, color.RGBA{255, 0, 0, 255}), Rect: image.Rect(1, 2, 3, 4), Opacity: 0.5},
+		{Name: "my photo.jpg", Image: uniform(3, 1, color.RGBA{0, 0, 255, 255}), Rect: image.Rect(0, 0, 3, 1), Opacity: 1},
+	}
+	if err := WriteSprites(dir, layers, 2); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "sprites.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet SpriteSheet
+	if err := json.Unmarshal(b, &sheet); err != nil {
+		t.Fatal(err)
+	}
+	want := []SpriteEntry{
+		{File: "00-back.png", Name: "back.png", Z: 0, X: 2, Y: 4, Width: 4, Height: 4, Opacity: 0.5},
+		{File: "01-my-photo.png", Name: "my photo.jpg", Z: 1, X: 0, Y: 0, Width: 6, Height: 2, Opacity: 1},
+	}
+	if len(sheet.Sprites) != len(want) {
+		t.Fatalf("got %v", sheet.Sprites)
+	}
+	for i, e := range want {
+		if sheet.Sprites[i] != e {
+			t.Errorf("%d: got %+v, want %+v", i, sheet.Sprites[i], e)
+		}
+	}
+	f, err := os.Open(filepath.Join(dir, "00-back.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// written at full opacity, the json has the sprite's opacity
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0xffff {
+		t.Errorf("alpha = %x, want opaque", a)
+	}
+}
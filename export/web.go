@@ -0,0 +1,119 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// writes layers, back to front, as an svg of region r with an image element
+// per layer. the images have scale times the pixels of their size on the
+// page, inline when embed is set, else pngs in a folder next to path
+func WriteSVG(path string, layers []Layer, r image.Rectangle, scale float64, embed bool) error {
+	hrefs, err := webImages(path, layers, scale, embed)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	w, h := r.Dx(), r.Dy()
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", w, h, w, h)
+	for i, l := range layers {
+		p := l.Rect.Sub(r.Min)
+		fmt.Fprintf(&b, `  <image id="sprite-%d" x="%d" y="%d" width="%d" height="%d" preserveAspectRatio="none"%s xlink:href="%s"><title>%s</title></image>`+"\n",
+			i, p.Min.X, p.Min.Y, p.Dx(), p.Dy(), opacityAttr(l.Opacity), hrefs[i], html.EscapeString(l.Name))
+	}
+	b.WriteString("</svg>\n")
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// writes layers, back to front, as an html page of region r with an
+// absolutely positioned img per layer. positions are percentages so the
+// collage scales with the page. images are stored as with WriteSVG
+func WriteHTML(path string, layers []Layer, r image.Rectangle, scale float64, embed bool) error {
+	hrefs, err := webImages(path, layers, scale, embed)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	w, h := r.Dx(), r.Dy()
+	fmt.Fprintf(&b, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%s</title>
+<style>
+.frame { position: relative; width: 100%%; max-width: %dpx; aspect-ratio: %d / %d; overflow: hidden; }
+.frame img { position: absolute; display: block; margin: 0; }
+</style>
+</head>
+<body>
+<div class="frame">
+`, html.EscapeString(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))), w, w, h)
+	for i, l := range layers {
+		p := l.Rect.Sub(r.Min)
+		style := fmt.Sprintf("left: %s; top: %s; width: %s; height: %s; z-index: %d;",
+			percent(p.Min.X, w), percent(p.Min.Y, h), percent(p.Dx(), w), percent(p.Dy(), h), i)
+		if l.Opacity < 1 {
+			style += " opacity: " + strconv.FormatFloat(clamp01(l.Opacity), 'f', -1, 64) + ";"
+		}
+		fmt.Fprintf(&b, `<img src="%s" alt="%s" style="%s">`+"\n", hrefs[i], html.EscapeString(l.Name), style)
+	}
+	b.WriteString("</div>\n</body>\n</html>\n")
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// the address of each layer's png: a data url, or a file written to the
+// folder named after path, relative to path
+func webImages(path string, layers []Layer, scale float64, embed bool) ([]string, error) {
+	dir := strings.TrimSuffix(path, filepath.Ext(path)) + "-images"
+	if !embed && len(layers) > 0 {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	hrefs := make([]string, len(layers))
+	for i, l := range layers {
+		img, err := layerPixels(l, l.Rect, scale)
+		if err != nil {
+			return nil, err
+		}
+		if embed {
+			var b bytes.Buffer
+			if err := Encode(&b, img, PNG, Options{}); err != nil {
+				return nil, err
+			}
+			hrefs[i] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes())
+			continue
+		}
+		file := fmt.Sprintf("%02d-%s.png", i, fileName(l.Name))
+		if err := writePNG(filepath.Join(dir, file), img); err != nil {
+			return nil, err
+		}
+		hrefs[i] = html.EscapeString(url.PathEscape(filepath.Base(dir)) + "/" + url.PathEscape(file))
+	}
+	return hrefs, nil
+}
+
+func opacityAttr(opacity float64) string {
+	if opacity >= 1 {
+		return ""
+	}
+	return ` opacity="` + strconv.FormatFloat(clamp01(opacity), 'f', -1, 64) + `"`
+}
+
+// v as a percentage of total, "12.5%"
+func percent(v, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return strconv.FormatFloat(float64(v)*100/float64(total), 'f', -1, 32) + "%"
+}
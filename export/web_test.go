@@ -0,0 +1,88 @@
+package export
+
+import (
+	"encoding/xml"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var webLayers = []Layer{
+	{Name: "back.png", Image: uniform(4, 2, color.RGBA{255, 0, 0, 255}), Rect: image.Rect(10, 20, 14, 22), Opacity: 1},
+	{Name: "a & b", Image: uniform(2, 2, color.RGBA{0, 0, 255, 255}), Rect: image.Rect(15, 25, 17, 27), Opacity: 0.5},
+}
+
+func TestWriteSVG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collage.svg")
+	if err := WriteSVG(path, webLayers, image.Rect(10, 20, 30, 30), 2, false); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var svg struct {
+		Width  int `xml:"width,attr"`
+		Images []struct {
+			X       int    `xml:"x,attr"`
+			Y       int    `xml:"y,attr"`
+			Width   int    `xml:"width,attr"`
+			Href    string `xml:"http://www.w3.org/1999/xlink href,attr"`
+			Opacity string `xml:"opacity,attr"`
+			Title   string `xml:"title"`
+		} `xml:"image"`
+	}
+	if err := xml.Unmarshal(data, &svg); err != nil {
+		t.Fatal(err)
+	}
+	if svg.Width != 20 || len(svg.Images) != 2 {
+		t.Fatalf("svg = %+v", svg)
+	}
+	if im := svg.Images[1]; im.X != 5 || im.Y != 5 || im.Width != 2 || im.Opacity != "0.5" || im.Title != "a & b" {
+		t.Errorf("image = %+v", im)
+	}
+	if href := svg.Images[0].Href; href != "collage-images/00-back.png" {
+		t.Errorf("href = %q", href)
+	}
+	f, err := os.Open(filepath.Join(filepath.Dir(path), svg.Images[0].Href))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil || cfg.Width != 8 {
+		t.Errorf("linked image: %v, width %d", err, cfg.Width)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "collage.html")
+	if err := WriteHTML(path, webLayers, image.Rect(10, 20, 30, 30), 1, true); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	back := strings.Index(page, `alt="back.png"`)
+	front := strings.Index(page, `alt="a &amp; b"`)
+	if back < 0 || front < back {
+		t.Errorf("images missing or out of order:\n%s", page)
+	}
+	for _, want := range []string{
+		`src="data:image/png;base64,`,
+		"left: 25%; top: 50%; width: 10%; height: 20%; z-index: 1; opacity: 0.5;",
+		"aspect-ratio: 20 / 10",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), "collage-images")); err == nil {
+		t.Error("embedded export wrote an image folder")
+	}
+}
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"frame/canvas"
+	"frame/control"
+	"frame/export"
 	"frame/ui"
+	"image"
 	"log"
+	"os"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -12,13 +19,143 @@ var (
 	initScreenHeight = 800
 )
 
+const usage = `usage: frame [flags] [image or folder ...]
+
+images are loaded in order, - reads an image from stdin.
+frame ctl -h shows the control client's usage.
+
+flags:
+`
+
 func main() {
-	ebiten.SetWindowSize(initScreenWidth, initScreenHeight)
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		os.Exit(ctl(os.Args[2:]))
+	}
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	width := flag.Int("width", initScreenWidth, "initial window width")
+	height := flag.Int("height", initScreenHeight, "initial window height")
+	layout := flag.String("layout", "cascade", "arrangement of loaded images: none, cascade or grid")
+	socket := flag.String("socket", control.DefaultSocketPath(), "control socket path, empty to disable")
+	host := flag.String("host", "", "share the canvas, listening on `addr` (e.g. :7777)")
+	join := flag.String("join", "", "work on the canvas shared at `addr`")
+	fit := flag.Float64("fit", ui.DefaultSettings().FitFraction, "scale imports down to this fraction of the window, 0 keeps their size")
+	watch := flag.String("watch", "", "import new images that appear in `dir`")
+	watchPos := flag.String("watch-pos", "0,0", "`x,y` position of images from the watched folder")
+	exifOrientation := flag.Bool("exif-orientation", true, "turn jpegs upright as their exif orientation says")
+	exportDir := flag.String("export-dir", "", "save exports to `dir` under generated names instead of asking")
+	exportFormat := flag.String("export-format", "png", "default export format: png, jpeg or gif")
+	exportSize := flag.String("export-size", "1x", "export scale like 2x, or a width like 1920px")
+	atlasPadding := flag.Int("atlas-padding", ui.DefaultSettings().AtlasPadding, "pixels around each sprite in exported atlases")
+	jpegQuality := flag.Int("jpeg-quality", ui.DefaultSettings().JPEGQuality, "jpeg export quality, 1 to 100")
+	timelapse := flag.Bool("timelapse", false, "record a timelapse of the canvas from the start")
+	timelapseWidth := flag.Int("timelapse-width", ui.DefaultSettings().TimelapseWidth, "width of timelapse frames in pixels")
+	timelapseDelay := flag.Duration("timelapse-delay", ui.DefaultSettings().TimelapseDelay, "how long each timelapse frame is shown")
+	timelapseLength := flag.Duration("timelapse-length", ui.DefaultSettings().TimelapseLength, "longest an exported timelapse runs, 0 for every frame")
+	pdfPage := flag.String("pdf-page", "a4 300dpi", "pdf export page size (fit, a3, a4, a5, letter, legal or tabloid) and dpi")
+	embedImages := flag.Bool("embed-images", true, "inline the images of svg and html exports instead of linking pngs")
+	gifFrames := flag.Bool("gif-frames", false, "import every frame of animated gifs as a strip of sprites")
+	flag.Parse()
+	l, err := canvas.ParseLayout(*layout)
+	if err != nil {
+		log.Fatal("-layout: ", err)
+	}
+	if *width < 1 {
+		log.Fatal("-width: must be positive")
+	}
+	if *height < 1 {
+		log.Fatal("-height: must be positive")
+	}
+
+	ebiten.SetWindowSize(*width, *height)
 	ebiten.SetWindowTitle("frame")
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 	ebiten.SetVsyncEnabled(true)
-	ui := ui.NewUI(initScreenWidth, initScreenHeight)
-	if err := ebiten.RunGame(ui); err != nil {
+	ui := ui.NewUI(*width, *height)
+	p, err := parsePoint(*watchPos)
+	if err != nil {
+		log.Fatal("-watch-pos: ", err)
+	}
+	settings := ui.Settings
+	settings.WatchPos = p
+	// -fit 0 only turns fitting off, toggling it back on uses the default
+	settings.FitImports = *fit > 0
+	if *fit > 0 {
+		settings.FitFraction = *fit
+	}
+	settings.GIFFrames = *gifFrames
+	settings.ExifOrientation = *exifOrientation
+	settings.ExportDir = *exportDir
+	if *jpegQuality < 1 || *jpegQuality > 100 {
+		log.Fatal("-jpeg-quality: must be 1 to 100")
+	}
+	settings.JPEGQuality = *jpegQuality
+	if *atlasPadding < 0 {
+		log.Fatal("-atlas-padding: must not be negative")
+	}
+	settings.AtlasPadding = *atlasPadding
+	settings.EmbedImages = *embedImages
+	if *timelapseWidth < 1 {
+		log.Fatal("-timelapse-width: must be positive")
+	}
+	settings.TimelapseWidth = *timelapseWidth
+	settings.TimelapseDelay = *timelapseDelay
+	settings.TimelapseLength = *timelapseLength
+	if settings.ExportFormat, err = export.ParseFormat(*exportFormat); err != nil {
+		log.Fatal("-export-format: ", err)
+	}
+	if settings.ExportSize, err = export.ParseSize(*exportSize); err != nil {
+		log.Fatal("-export-size: ", err)
+	}
+	if settings.PDFPage, err = export.ParsePage(*pdfPage); err != nil {
+		log.Fatal("-pdf-page: ", err)
+	}
+	ui.SetSettings(settings)
+	if *timelapse {
+		ui.StartTimelapse()
+	}
+	if flag.NArg() > 0 {
+		ui.Open(flag.Args(), l)
+	}
+	if *watch != "" {
+		if err := ui.Watch(*watch); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *host != "" {
+		if err := ui.Host(*host); err != nil {
+			log.Fatal(err)
+		}
+	} else if *join != "" {
+		if err := ui.Join(*join); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// listens last and closes before any exit, so the socket file is removed
+	var srv *control.Server
+	if *socket != "" {
+		if srv, err = control.Listen(*socket, ui); err != nil {
+			log.Println("no control socket:", err)
+		} else {
+			go srv.Serve()
+		}
+	}
+	err = ebiten.RunGame(ui)
+	if srv != nil {
+		srv.Close()
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// parses "x,y"
+func parsePoint(s string) (image.Point, error) {
+	var p image.Point
+	if _, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y); err != nil {
+		return p, fmt.Errorf("%q is not x,y", s)
+	}
+	return p, nil
+}
@@ -0,0 +1,77 @@
+// Package meta describes where sprites came from, so collages can credit
+// their sources
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// one source of a sprite's pixels
+type Credit struct {
+	// file name, or where the image came from when it is not a file
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+	// size of the original image in pixels
+	Width    int       `json:"width"`
+	Height   int       `json:"height"`
+	Imported time.Time `json:"imported"`
+	// from the exif tags of the file
+	Artist    string `json:"artist,omitempty"`
+	Copyright string `json:"copyright,omitempty"`
+}
+
+func (c Credit) key() string {
+	if c.Path != "" {
+		return c.Path
+	}
+	return c.Name + "\x00" + c.Imported.String()
+}
+
+// combines lists of credits, each source once, in the order first seen
+func Merge(lists ...[]Credit) []Credit {
+	seen := map[string]bool{}
+	merged := []Credit{}
+	for _, list := range lists {
+		for _, c := range list {
+			if seen[c.key()] {
+				continue
+			}
+			seen[c.key()] = true
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
+
+// writes credits as an indented json array
+func WriteJSON(w io.Writer, credits []Credit) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(credits)
+}
+
+// writes credits as a markdown list
+func WriteMarkdown(w io.Writer, credits []Credit) error {
+	var b strings.Builder
+	b.WriteString("# sources\n\n")
+	for _, c := range credits {
+		fmt.Fprintf(&b, "- **%s**, %dx%d", c.Name, c.Width, c.Height)
+		if c.Artist != "" {
+			fmt.Fprintf(&b, ", by %s", c.Artist)
+		}
+		if c.Copyright != "" {
+			fmt.Fprintf(&b, ", %s", c.Copyright)
+		}
+		b.WriteString("\n")
+		if c.Path != "" {
+			fmt.Fprintf(&b, "  - path: `%s`\n", c.Path)
+		}
+		fmt.Fprintf(&b, "  - imported: %s\n", c.Imported.Format(time.RFC3339))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,59 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	t0 := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	a := Credit{Name: "a.png", Path: "/x/a.png"}
+	b := Credit{Name: "b.png", Path: "/x/b.png"}
+	paste1 := Credit{Name: "clipboard", Imported: t0}
+	paste2 := Credit{Name: "clipboard", Imported: t0.Add(time.Second)}
+	got := Merge([]Credit{a, paste1}, []Credit{b, a, paste1, paste2})
+	want := []Credit{a, paste1, b, paste2}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	credits := []Credit{{
+		Name:      "cat.jpg",
+		Path:      "/photos/cat.jpg",
+		Width:     4032,
+		Height:    3024,
+		Imported:  time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+		Artist:    "Jo",
+		Copyright: "CC BY 4.0",
+	}}
+	var md bytes.Buffer
+	if err := WriteMarkdown(&md, credits); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"**cat.jpg**, 4032x3024, by Jo, CC BY 4.0", "`/photos/cat.jpg`", "2023-05-01T12:00:00Z"} {
+		if !strings.Contains(md.String(), s) {
+			t.Errorf("markdown missing %q:\n%s", s, md.String())
+		}
+	}
+	var js bytes.Buffer
+	if err := WriteJSON(&js, credits); err != nil {
+		t.Fatal(err)
+	}
+	var back []Credit
+	if err := json.Unmarshal(js.Bytes(), &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != 1 || back[0] != credits[0] {
+		t.Errorf("json round trip = %v", back)
+	}
+}
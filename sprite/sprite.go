@@ -1,11 +1,14 @@
 package sprite
 
 import (
+	"frame/anim"
 	"frame/draw"
+	"frame/meta"
 	"image"
 	"image/color"
 	"log"
 	"math"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/colorm"
@@ -15,6 +18,85 @@ type Sprite struct {
 	Image         *ebiten.Image
 	Pos           image.Point
 	OpacityOffset float64
+	// file the sprite was made from, nil if none
+	Source *Source
+	// every source of the sprite's pixels, for attribution
+	Credits []meta.Credit
+	// hash of the pixels the sprite was imported with, empty if unknown.
+	// copies keep it, crops are new images
+	Hash string
+	// frames of an animated sprite and how long each is shown, Image is the
+	// one showing. empty for still sprites
+	Frames []*ebiten.Image
+	Delays []time.Duration
+	frame  int
+}
+
+// links a sprite to the image or file it was made from
+type Source struct {
+	// empty if the image did not come from a file
+	Path    string
+	ModTime time.Time
+	// full resolution pixels, kept when the sprite was scaled down on import
+	Image image.Image
+	// region of the file's image the sprite shows
+	Rect image.Rectangle
+	// set after edits that can't be reapplied to a new version of the file
+	Detached bool
+	// set when pixels were cut out of the sprite, so Image has pixels the
+	// sprite does not
+	Cut bool
+	// set when the file changed but the sprite could not be reloaded
+	Changed bool
+}
+
+// source region shown by r, in sprite coordinates, for a sprite of size
+func (src Source) sub(r image.Rectangle, size image.Point) image.Rectangle {
+	sr := src.Rect
+	if size.X < 1 || size.Y < 1 {
+		return sr
+	}
+	return image.Rect(
+		sr.Min.X+r.Min.X*sr.Dx()/size.X,
+		sr.Min.Y+r.Min.Y*sr.Dy()/size.Y,
+		sr.Min.X+r.Max.X*sr.Dx()/size.X,
+		sr.Min.Y+r.Max.Y*sr.Dy()/size.Y,
+	)
+}
+
+func (src *Source) copy() *Source {
+	if src == nil {
+		return nil
+	}
+	c := *src
+	return &c
+}
+
+func (s Sprite) Animated() bool {
+	return len(s.Frames) > 1
+}
+
+// shows the frame due t into the looping animation
+func (s *Sprite) Animate(t time.Duration) {
+	if !s.Animated() {
+		return
+	}
+	s.frame = anim.FrameAt(s.Delays, t)
+	s.Image = s.Frames[s.frame]
+}
+
+// replaces every frame, or the image of a still sprite, with f of it
+func (s *Sprite) mapFrames(f func(*ebiten.Image) *ebiten.Image) {
+	if len(s.Frames) == 0 {
+		s.Image = f(s.Image)
+		return
+	}
+	frames := make([]*ebiten.Image, len(s.Frames))
+	for i, fr := range s.Frames {
+		frames[i] = f(fr)
+	}
+	s.Frames = frames
+	s.Image = frames[s.frame]
 }
 
 // returns true if point is within the bounds of the sprite
@@ -65,29 +147,52 @@ func (s Sprite) DrawInverted(dst *ebiten.Image, dv image.Point, alpha float64) {
 	draw.DrawImageInverted(dst, s.Image, s.Pos.Add(dv), alpha)
 }
 
+// adds o to the opacity offset, clamped to [-1, 0]
+func (s *Sprite) AddOpacity(o float64) {
+	o += s.OpacityOffset
+	if o > 0 {
+		o = 0
+	} else if o < -1 {
+		o = -1
+	}
+	s.OpacityOffset = o
+}
+
 // resize, keep position
 func (s *Sprite) Resize(newSize image.Point) {
 	r := image.Rect(0, 0, newSize.X, newSize.Y)
-	i := draw.ResizeImage(s.Image, r)
-	s.Image = i
+	s.mapFrames(func(i *ebiten.Image) *ebiten.Image {
+		return draw.ResizeImage(i, r)
+	})
 }
 
 // resize, set position
 func (s *Sprite) Reshape(r image.Rectangle) {
 	r = r.Canon()
 	newSize := r.Sub(r.Min)
-	i := draw.ResizeImage(s.Image, newSize)
-	s.Image = i
+	s.mapFrames(func(i *ebiten.Image) *ebiten.Image {
+		return draw.ResizeImage(i, newSize)
+	})
 	s.Pos = r.Min
 }
 
 // returns a pointer to a new copy of the sprite
 func (s *Sprite) Copy() *Sprite {
-	return &Sprite{
-		Image:         ebiten.NewImageFromImage(s.Image),
+	c := &Sprite{
+		Image:         s.Image,
 		Pos:           s.Pos,
 		OpacityOffset: s.OpacityOffset,
+		Source:        s.Source.copy(),
+		Credits:       meta.Merge(s.Credits),
+		Hash:          s.Hash,
+		Frames:        s.Frames,
+		Delays:        s.Delays,
+		frame:         s.frame,
 	}
+	c.mapFrames(func(i *ebiten.Image) *ebiten.Image {
+		return ebiten.NewImageFromImage(i)
+	})
+	return c
 }
 
 func (s *Sprite) Crop(r image.Rectangle) *Sprite {
@@ -95,9 +200,61 @@ func (s *Sprite) Crop(r image.Rectangle) *Sprite {
 	if im == nil {
 		return nil
 	}
-	return &Sprite{
-		Image: im,
-		Pos:   nr.Min,
+	c := &Sprite{
+		Image:   im,
+		Pos:     nr.Min,
+		Source:  s.Source.copy(),
+		Credits: meta.Merge(s.Credits),
+		Delays:  s.Delays,
+		frame:   s.frame,
+	}
+	if len(s.Frames) > 0 {
+		c.Frames = s.Frames
+		c.mapFrames(func(i *ebiten.Image) *ebiten.Image {
+			im, _ := draw.CropImage(i, r, s.Pos.Mul(-1))
+			return im
+		})
+	}
+	if c.Source != nil {
+		c.Source.Rect = s.Source.sub(nr.Sub(s.Pos), s.Image.Bounds().Size())
+	}
+	return c
+}
+
+// replaces the image with the source region of img, a new version of the
+// source file, scaled to the current size. marks the source as changed and
+// returns false if that isn't possible
+func (s *Sprite) Reload(img image.Image, modTime time.Time) bool {
+	src := s.Source
+	src.ModTime = modTime
+	if img == nil || src.Detached || s.Animated() || !src.Rect.In(img.Bounds()) {
+		src.Changed = true
+		return false
+	}
+	// scaled on the cpu, the source may be too large to upload
+	i := draw.SubImage(img, src.Rect)
+	if size := s.Image.Bounds().Size(); i.Bounds().Size() != size {
+		i = draw.Scale(i, size)
+	}
+	s.Image = ebiten.NewImageFromImage(i)
+	if src.Image != nil {
+		src.Image = img
+	}
+	src.Changed = false
+	return true
+}
+
+// cuts r, in canvas coordinates, out of the sprite. the image is replaced,
+// not modified, so sprites can share images
+func (s *Sprite) Cut(r image.Rectangle) {
+	s.mapFrames(func(img *ebiten.Image) *ebiten.Image {
+		i := ebiten.NewImageFromImage(img)
+		draw.CutImage(i, r.Sub(s.Pos))
+		return i
+	})
+	if s.Source != nil {
+		s.Source.Detached = true
+		s.Source.Cut = true
 	}
 }
 
@@ -200,6 +357,65 @@ func (list SpriteList) SendToBack(s *Sprite) SpriteList {
 	return list
 }
 
+// moves sprites to the front as one block, keeping their order
+func (list SpriteList) BringAllToFront(sprites []*Sprite) SpriteList {
+	in, out := list.partition(sprites)
+	return append(in, out...)
+}
+
+// moves sprites to the back as one block, keeping their order
+func (list SpriteList) SendAllToBack(sprites []*Sprite) SpriteList {
+	in, out := list.partition(sprites)
+	return append(out, in...)
+}
+
+// moves sprites one step towards the front. adjacent ones move together,
+// a sprite never swaps with another of sprites
+func (list SpriteList) BringAllForwards(sprites []*Sprite) SpriteList {
+	set := spriteSet(sprites)
+	l := append(SpriteList{}, list...)
+	for i := 1; i < len(l); i++ {
+		if set[l[i]] && !set[l[i-1]] {
+			l[i-1], l[i] = l[i], l[i-1]
+		}
+	}
+	return l
+}
+
+// moves sprites one step towards the back, like BringAllForwards
+func (list SpriteList) SendAllBackwards(sprites []*Sprite) SpriteList {
+	set := spriteSet(sprites)
+	l := append(SpriteList{}, list...)
+	for i := len(l) - 2; i >= 0; i-- {
+		if set[l[i]] && !set[l[i+1]] {
+			l[i], l[i+1] = l[i+1], l[i]
+		}
+	}
+	return l
+}
+
+func spriteSet(sprites []*Sprite) map[*Sprite]bool {
+	set := map[*Sprite]bool{}
+	for _, s := range sprites {
+		set[s] = true
+	}
+	return set
+}
+
+// the sprites of list in sprites and the others, in list order
+func (list SpriteList) partition(sprites []*Sprite) (in, out SpriteList) {
+	set := spriteSet(sprites)
+	in, out = SpriteList{}, SpriteList{}
+	for _, s := range list {
+		if set[s] {
+			in = append(in, s)
+		} else {
+			out = append(out, s)
+		}
+	}
+	return in, out
+}
+
 func (list SpriteList) Remove(s *Sprite) SpriteList {
 	i := list.IndexOf(s)
 	if i == -1 {
@@ -1,6 +1,7 @@
 package sprite
 
 import (
+	"image"
 	"reflect"
 	"testing"
 )
@@ -59,3 +60,90 @@ func TestSpriteList_BringForwards(t *testing.T) {
 		})
 	}
 }
+
+func TestSpriteList_AllToFrontBack(t *testing.T) {
+	l := MakeSpriteList()
+	// selected back to front, they keep their list order
+	moved := []*Sprite{l[3], l[1]}
+	front := append(SpriteList{}, l...).BringAllToFront(moved)
+	if want := (SpriteList{l[1], l[3], l[0], l[2], l[4]}); !sameSprites(front, want) {
+		t.Errorf("BringAllToFront() = %v, want %v", front, want)
+	}
+	back := append(SpriteList{}, l...).SendAllToBack(moved)
+	if want := (SpriteList{l[0], l[2], l[4], l[1], l[3]}); !sameSprites(back, want) {
+		t.Errorf("SendAllToBack() = %v, want %v", back, want)
+	}
+}
+
+func TestSpriteList_AllForwardsBackwards(t *testing.T) {
+	l := MakeSpriteList()
+	tests := []struct {
+		name           string
+		moved          []*Sprite
+		forwards, back SpriteList
+	}{
+		{
+			name:     "adjacent",
+			moved:    []*Sprite{l[2], l[1]},
+			forwards: SpriteList{l[1], l[2], l[0], l[3], l[4]},
+			back:     SpriteList{l[0], l[3], l[1], l[2], l[4]},
+		},
+		{
+			name:     "at the ends",
+			moved:    []*Sprite{l[0], l[1], l[4]},
+			forwards: SpriteList{l[0], l[1], l[2], l[4], l[3]},
+			back:     SpriteList{l[2], l[0], l[1], l[3], l[4]},
+		},
+	}
+	for _, tt := range tests {
+		if got := l.BringAllForwards(tt.moved); !sameSprites(got, tt.forwards) {
+			t.Errorf("%s: BringAllForwards() = %v, want %v", tt.name, got, tt.forwards)
+		}
+		if got := l.SendAllBackwards(tt.moved); !sameSprites(got, tt.back) {
+			t.Errorf("%s: SendAllBackwards() = %v, want %v", tt.name, got, tt.back)
+		}
+	}
+}
+
+// compares identity, the sprites of MakeSpriteList are deeply equal
+func sameSprites(a, b SpriteList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSource_sub(t *testing.T) {
+	src := Source{Rect: image.Rect(10, 10, 210, 110)}
+	tests := []struct {
+		name string
+		r    image.Rectangle
+		size image.Point
+		want image.Rectangle
+	}{
+		{
+			name: "unscaled",
+			r:    image.Rect(5, 5, 50, 50),
+			size: image.Pt(200, 100),
+			want: image.Rect(15, 15, 60, 60),
+		},
+		{
+			name: "half size",
+			r:    image.Rect(0, 0, 50, 25),
+			size: image.Pt(100, 50),
+			want: image.Rect(10, 10, 110, 60),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := src.sub(tt.r, tt.size); got != tt.want {
+				t.Errorf("Source.sub() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	imagedraw "image/draw"
+	"os"
+	"sort"
+	"time"
+
+	"frame/anim"
+	"frame/draw"
+)
+
+// exported animations are cut short after this many frames
+var animationMaxFrames = 500
+
+// canvas readbacks per tick while exporting an animation, so the window
+// stays responsive
+var animationChunk = 4
+
+// writes the canvas as an animated gif, over the longest animation's loop.
+// frames are read back a few per tick, then composited and encoded off the
+// game loop
+type ExportAnimationOp struct {
+	prompt *PromptOp
+	path   string
+	times  []time.Duration
+	delays []time.Duration
+	frames []image.Image
+}
+
+func (op ExportAnimationOp) String() string {
+	if op.times != nil {
+		return fmt.Sprintf("export animation %d/%d", len(op.frames), len(op.times))
+	}
+	return "export animation"
+}
+
+func (op *ExportAnimationOp) Update(ui *UI) (done bool, err error) {
+	if op.times == nil {
+		if ui.animationLength() == 0 {
+			return true, errors.New("no animated sprites")
+		}
+		path, ok := ui.exportTarget(&op.prompt, "save animation as", "-animation", ".gif")
+		if !ok {
+			return false, nil
+		}
+		if path == "" {
+			return true, nil
+		}
+		op.path = path
+		op.times, op.delays = ui.animationTimes()
+		ui.notify("saving " + op.path)
+	}
+	// the live animation is put back when Update draws the canvas
+	for n := 0; n < animationChunk && len(op.frames) < len(op.times); n++ {
+		ui.Canvas.Animate(op.times[len(op.frames)])
+		ui.Canvas.DrawSprites()
+		op.frames = append(op.frames, draw.ToRGBA(ui.Canvas.Image()))
+	}
+	if len(op.frames) < len(op.times) {
+		return false, nil
+	}
+	path, frames, delays := op.path, op.frames, op.delays
+	go func() {
+		for i, f := range frames {
+			frames[i] = overWhite(f)
+		}
+		if err := writeGIF(path, frames, delays); err != nil {
+			ui.notifyError("export animation", err)
+			return
+		}
+		ui.notify("saved " + path)
+	}()
+	return true, nil
+}
+
+// loop length of the longest animation on the canvas, zero if none
+func (ui *UI) animationLength() time.Duration {
+	var length time.Duration
+	for _, sp := range ui.Canvas.Sprites {
+		if l := anim.Length(sp.Delays); sp.Animated() && l > length {
+			length = l
+		}
+	}
+	return length
+}
+
+// the times at which any sprite changes frame, and how long each frame of
+// the canvas is shown
+func (ui *UI) animationTimes() ([]time.Duration, []time.Duration) {
+	length := ui.animationLength()
+	changes := map[time.Duration]bool{0: true}
+	for _, sp := range ui.Canvas.Sprites {
+		if !sp.Animated() {
+			continue
+		}
+		for t := time.Duration(0); t < length; {
+			for _, d := range sp.Delays {
+				t += anim.Shown(d)
+				if t >= length {
+					break
+				}
+				changes[t] = true
+			}
+		}
+	}
+	times := make([]time.Duration, 0, len(changes))
+	for t := range changes {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	if len(times) > animationMaxFrames {
+		times = times[:animationMaxFrames]
+		length = times[len(times)-1] + anim.Shown(0)
+	}
+
+	delays := make([]time.Duration, len(times))
+	for i, t := range times {
+		if i+1 < len(times) {
+			delays[i] = times[i+1] - t
+		} else {
+			delays[i] = length - t
+		}
+	}
+	return times, delays
+}
+
+// img drawn over a white background
+func overWhite(img image.Image) image.Image {
+	dst := image.NewRGBA(img.Bounds())
+	imagedraw.Draw(dst, dst.Bounds(), image.White, image.Point{}, imagedraw.Src)
+	imagedraw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, imagedraw.Over)
+	return dst
+}
+
+func writeGIF(path string, frames []image.Image, delays []time.Duration) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := anim.EncodeGIF(f, frames, delays); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
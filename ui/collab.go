@@ -0,0 +1,379 @@
+package ui
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"frame/collab"
+	"frame/draw"
+	"frame/sprite"
+)
+
+var collabClr = color.RGBA{148, 0, 211, 255} // violet
+
+// shared canvas state. changes are found by comparing the canvas against
+// what was last sent or received
+type collabState struct {
+	session *collab.Session
+	next    uint64
+	ids     map[*sprite.Sprite]uint64
+	sprites map[uint64]*sprite.Sprite
+	synced  map[uint64]syncedSprite
+	order   []uint64
+	rects   []image.Rectangle
+	remote  map[uint32][]image.Rectangle
+}
+
+type syncedSprite struct {
+	img     *ebiten.Image
+	pos     image.Point
+	opacity float64
+}
+
+// shares the canvas with instances that join addr
+func (ui *UI) Host(addr string) error {
+	s, err := collab.Host(addr)
+	if err != nil {
+		return err
+	}
+	log.Println("collab: hosting on", s.Addr())
+	ui.startCollab(s)
+	return nil
+}
+
+// joins the canvas hosted at addr, replacing the local sprites
+func (ui *UI) Join(addr string) error {
+	s, err := collab.Join(addr)
+	if err != nil {
+		return err
+	}
+	// nothing local is sent, the host's snapshot replaces it
+	ui.Canvas.ClearSprites()
+	ui.startCollab(s)
+	return nil
+}
+
+func (ui *UI) startCollab(s *collab.Session) {
+	ui.collab = &collabState{
+		session: s,
+		ids:     map[*sprite.Sprite]uint64{},
+		sprites: map[uint64]*sprite.Sprite{},
+		synced:  map[uint64]syncedSprite{},
+		remote:  map[uint32][]image.Rectangle{},
+	}
+}
+
+// sends local changes, then applies received ones in the host's order.
+// guests get their own changes back in that order too, so every peer ends
+// up with whichever change the host applied last
+func (ui *UI) syncCollab() {
+	c := ui.collab
+	if c == nil {
+		return
+	}
+	ui.sendChanges()
+	applied := false
+	for {
+		m, ok := c.session.Poll()
+		if !ok {
+			break
+		}
+		ui.applyMsg(m)
+		applied = true
+	}
+	if applied {
+		c.order = c.currentOrder(ui.Canvas.Sprites)
+	}
+}
+
+func (c *collabState) track(id uint64, sp *sprite.Sprite) {
+	c.ids[sp] = id
+	c.sprites[id] = sp
+	c.synced[id] = syncedSprite{pixels(sp), sp.Pos, sp.OpacityOffset}
+}
+
+// changes only when the sprite's pixels do. the image of an animated sprite
+// changes every frame, its first frame does not
+func pixels(sp *sprite.Sprite) *ebiten.Image {
+	if len(sp.Frames) > 0 {
+		return sp.Frames[0]
+	}
+	return sp.Image
+}
+
+// the sprite's pixels for sending
+func stateImages(state *collab.SpriteState, sp *sprite.Sprite) {
+	state.Image = draw.ToRGBA(sp.Image)
+	if !sp.Animated() {
+		return
+	}
+	for _, f := range sp.Frames {
+		state.Frames = append(state.Frames, draw.ToRGBA(f))
+	}
+	state.Delays = sp.Delays
+}
+
+// sets the sprite's pixels from a received state with images
+func applyImages(sp *sprite.Sprite, state collab.SpriteState) {
+	sp.Image = ebiten.NewImageFromImage(state.Image)
+	sp.Frames, sp.Delays = nil, nil
+	if len(state.Frames) == 0 {
+		return
+	}
+	for _, f := range state.Frames {
+		sp.Frames = append(sp.Frames, ebiten.NewImageFromImage(f))
+	}
+	sp.Delays = state.Delays
+	sp.Image = sp.Frames[0]
+}
+
+func (c *collabState) forget(id uint64) {
+	delete(c.ids, c.sprites[id])
+	delete(c.sprites, id)
+	delete(c.synced, id)
+}
+
+func (c *collabState) reset() {
+	c.ids = map[*sprite.Sprite]uint64{}
+	c.sprites = map[uint64]*sprite.Sprite{}
+	c.synced = map[uint64]syncedSprite{}
+	c.order = nil
+}
+
+// ids are unique across peers
+func (c *collabState) newID() uint64 {
+	c.next++
+	return uint64(c.session.Self)<<32 | c.next
+}
+
+// ids of tracked sprites, front to back
+func (c *collabState) currentOrder(sprites sprite.SpriteList) []uint64 {
+	order := []uint64{}
+	for _, sp := range sprites {
+		if id, ok := c.ids[sp]; ok {
+			order = append(order, id)
+		}
+	}
+	return order
+}
+
+func (ui *UI) sendChanges() {
+	c := ui.collab
+	seen := map[uint64]bool{}
+	for _, sp := range ui.Canvas.Sprites {
+		id, ok := c.ids[sp]
+		if !ok {
+			id = c.newID()
+			c.ids[sp] = id
+			c.sprites[id] = sp
+		}
+		seen[id] = true
+		prev, ok := c.synced[id]
+		if ok && prev.img == pixels(sp) && prev.pos == sp.Pos && prev.opacity == sp.OpacityOffset {
+			continue
+		}
+		state := collab.SpriteState{
+			ID:            id,
+			Pos:           sp.Pos,
+			OpacityOffset: sp.OpacityOffset,
+		}
+		if !ok || prev.img != pixels(sp) {
+			stateImages(&state, sp)
+		}
+		c.session.Send(collab.Msg{Kind: collab.KindSprite, Sprite: state})
+		c.synced[id] = syncedSprite{pixels(sp), sp.Pos, sp.OpacityOffset}
+	}
+	for id := range c.synced {
+		if !seen[id] {
+			c.session.Send(collab.Msg{Kind: collab.KindDelete, ID: id})
+			c.forget(id)
+		}
+	}
+	if order := c.currentOrder(ui.Canvas.Sprites); !equalIDs(order, c.order) {
+		c.session.Send(collab.Msg{Kind: collab.KindOrder, Order: order})
+		c.order = order
+	}
+	if rects := ui.selectionRects(); !equalRects(rects, c.rects) {
+		c.session.Send(collab.Msg{Kind: collab.KindSelection, Rects: rects})
+		c.rects = rects
+	}
+}
+
+func (ui *UI) applyMsg(m collab.Msg) {
+	c := ui.collab
+	switch m.Kind {
+	case collab.KindJoin:
+		if c.session.IsHost() {
+			ui.notify("collab: a peer joined")
+			c.session.Send(collab.Msg{Kind: collab.KindSnapshot, To: m.From, Sprites: ui.snapshot()})
+		}
+	case collab.KindLeave:
+		delete(c.remote, m.From)
+		if m.From == 0 {
+			ui.notifyError("collab", errors.New("disconnected from host"))
+		} else if c.session.IsHost() {
+			ui.notify("collab: a peer left")
+		}
+	case collab.KindSnapshot:
+		ui.Canvas.ClearSprites()
+		c.reset()
+		for _, state := range m.Sprites {
+			sp := &sprite.Sprite{
+				Pos:           state.Pos,
+				OpacityOffset: state.OpacityOffset,
+			}
+			applyImages(sp, state)
+			ui.Canvas.Sprites = append(ui.Canvas.Sprites, sp)
+			c.track(state.ID, sp)
+		}
+	case collab.KindSprite:
+		state := m.Sprite
+		sp, ok := c.sprites[state.ID]
+		if !ok {
+			if state.Image == nil {
+				return
+			}
+			sp = &sprite.Sprite{}
+			ui.Canvas.AddSprite(sp)
+		}
+		if state.Image != nil {
+			applyImages(sp, state)
+		}
+		sp.Pos = state.Pos
+		sp.OpacityOffset = state.OpacityOffset
+		c.track(state.ID, sp)
+	case collab.KindDelete:
+		if sp, ok := c.sprites[m.ID]; ok {
+			ui.Canvas.RemoveSprite(sp)
+			c.forget(m.ID)
+		}
+	case collab.KindOrder:
+		list := sprite.SpriteList{}
+		for _, id := range m.Order {
+			if sp, ok := c.sprites[id]; ok && ui.Canvas.Sprites.IndexOf(sp) != -1 {
+				list = append(list, sp)
+			}
+		}
+		// sprites the sender did not know about yet stay in front
+		for i := len(ui.Canvas.Sprites) - 1; i >= 0; i-- {
+			if sp := ui.Canvas.Sprites[i]; list.IndexOf(sp) == -1 {
+				list = append(sprite.SpriteList{sp}, list...)
+			}
+		}
+		ui.Canvas.Sprites = list
+	case collab.KindSelection:
+		if m.From != c.session.Self {
+			c.remote[m.From] = m.Rects
+		}
+	}
+}
+
+// full sprite list for a joining peer
+func (ui *UI) snapshot() []collab.SpriteState {
+	c := ui.collab
+	states := []collab.SpriteState{}
+	for _, sp := range ui.Canvas.Sprites {
+		id, ok := c.ids[sp]
+		if !ok {
+			continue
+		}
+		state := collab.SpriteState{
+			ID:            id,
+			Pos:           sp.Pos,
+			OpacityOffset: sp.OpacityOffset,
+		}
+		stateImages(&state, sp)
+		states = append(states, state)
+	}
+	return states
+}
+
+// outlines of the selections of the operations in progress
+func (ui *UI) selectionRects() []image.Rectangle {
+	rects := []image.Rectangle{}
+	addSprites := func(sprites []*sprite.Sprite, v image.Point) {
+		for _, sp := range sprites {
+			rects = append(rects, sp.Rect().Add(v))
+		}
+	}
+	addDrag := func(d MouseDrag) {
+		if d.Started {
+			rects = append(rects, d.Rect())
+		}
+	}
+	for _, o := range ui.operations {
+		switch op := o.(type) {
+		case *SelectSpriteMultiOp:
+			addSprites(op.Targets, image.Point{})
+			addDrag(op.drag)
+		case *SelectSpriteRectOp:
+			if op.selDrag.Moved() {
+				addDrag(op.selDrag)
+			} else if op.target != nil {
+				addSprites([]*sprite.Sprite{op.target}, image.Point{})
+			}
+		case *SelectSpriteOp:
+			if op.target != nil {
+				addSprites([]*sprite.Sprite{op.target}, image.Point{})
+			}
+		case *MoveOp:
+			v := image.Point{}
+			if op.drag.Started {
+				v = op.drag.Diff()
+			}
+			addSprites(op.Targets, v)
+		case *CropOp:
+			addSprites(op.Targets, image.Point{})
+			addDrag(op.drag)
+		case *CutOp:
+			addSprites(op.Targets, image.Point{})
+			addDrag(op.drag)
+		case *FlattenOp:
+			addDrag(op.drag)
+		case *ExportOp:
+			if op.scope == exportRegion && op.rect.Empty() {
+				addDrag(op.drag)
+			}
+		}
+	}
+	return rects
+}
+
+func (ui *UI) drawCollab(dst *ebiten.Image) {
+	if ui.collab == nil {
+		return
+	}
+	for _, rects := range ui.collab.remote {
+		for _, r := range rects {
+			draw.StrokeRect(dst, r, collabClr, 2, 0)
+		}
+	}
+}
+
+func equalIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalRects(a, b []image.Rectangle) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
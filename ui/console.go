@@ -0,0 +1,337 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"frame/draw"
+	"frame/sprite"
+)
+
+var (
+	consoleLines   = 8
+	consolePadding = 4
+	consoleFg      = color.Black
+	consoleBg      = color.RGBA{255, 255, 255, 230}
+	consoleClr     = color.RGBA{255, 0, 255, 255} // magenta
+)
+
+type consoleCommand struct {
+	usage string
+	run   func(ui *UI, args []string) (string, error)
+}
+
+var consoleCommands map[string]consoleCommand
+
+func init() {
+	consoleCommands = map[string]consoleCommand{
+		"help": {
+			usage: "help",
+			run:   consoleHelp,
+		},
+		"clear": {
+			usage: "clear",
+			run: func(ui *UI, args []string) (string, error) {
+				ui.console.lines = nil
+				return "", nil
+			},
+		},
+		"select": {
+			usage: "select all|none|duplicates",
+			run:   consoleSelect,
+		},
+		"move": {
+			usage: "move dx dy",
+			run:   consoleMove,
+		},
+		"moveto": {
+			usage: "moveto x y",
+			run:   consoleMoveTo,
+		},
+		"reshape": {
+			usage: "reshape w h | reshape x y w h",
+			run:   consoleReshape,
+		},
+		"opacity": {
+			usage: "opacity offset",
+			run:   consoleOpacity,
+		},
+		"reorder": {
+			usage: "reorder front|back|forward|backward",
+			run:   consoleReorder,
+		},
+		"delete": {
+			usage: "delete",
+			run:   consoleDelete,
+		},
+	}
+}
+
+// text console, applies typed commands to the selection
+type ConsoleOp struct {
+	input   TextInput
+	lines   []string
+	targets []*sprite.Sprite
+	fresh   bool
+
+	img    *ebiten.Image
+	imgTxt string
+}
+
+func (op ConsoleOp) String() string { return "console" }
+
+func (ui *UI) openConsole() *ConsoleOp {
+	if ui.console == nil {
+		ui.console = &ConsoleOp{}
+	}
+	ui.console.fresh = true
+	return ui.console
+}
+
+func (op *ConsoleOp) Update(ui *UI) (done bool, err error) {
+	op.targets = ui.Selection()
+	// skip the key press that opened the console
+	if op.fresh {
+		op.fresh = false
+		return false, nil
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackquote) {
+		return true, nil
+	}
+	if !op.input.Update() {
+		return false, nil
+	}
+	line := strings.TrimSpace(op.input.Text)
+	op.input.Text = ""
+	if line == "" {
+		return false, nil
+	}
+	op.println("> " + line)
+	out, err := ui.runCommand(line)
+	if err != nil {
+		op.println("error: " + err.Error())
+	} else if out != "" {
+		op.println(out)
+	}
+	op.targets = ui.Selection()
+	return false, nil
+}
+
+func (op *ConsoleOp) println(txt string) {
+	op.lines = append(op.lines, strings.Split(txt, "\n")...)
+}
+
+func (op *ConsoleOp) Draw(dst *ebiten.Image) {
+	for _, sp := range op.targets {
+		sp.Outline(dst, consoleClr, 1, -1)
+	}
+	lines := op.lines
+	if len(lines) > consoleLines {
+		lines = lines[len(lines)-consoleLines:]
+	}
+	txt := strings.Join(append(lines, "> "+op.input.Text+"_"), "\n")
+	if op.img == nil || txt != op.imgTxt {
+		op.img = draw.NewTextBlockImage(txt, draw.Font, consolePadding, consoleFg, consoleBg)
+		op.imgTxt = txt
+	}
+	opts := &ebiten.DrawImageOptions{}
+	opts.GeoM.Translate(0, float64(dst.Bounds().Dy()-op.img.Bounds().Dy()))
+	dst.DrawImage(op.img, opts)
+}
+
+// parses and runs one console command line
+func (ui *UI) runCommand(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	cmd, ok := consoleCommands[fields[0]]
+	if !ok {
+		return "", fmt.Errorf("unknown command %q, try help", fields[0])
+	}
+	return cmd.run(ui, fields[1:])
+}
+
+func consoleHelp(ui *UI, args []string) (string, error) {
+	usages := []string{}
+	for _, cmd := range consoleCommands {
+		usages = append(usages, cmd.usage)
+	}
+	sort.Strings(usages)
+	return strings.Join(usages, "\n"), nil
+}
+
+func consoleSelect(ui *UI, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", usageError("select")
+	}
+	switch args[0] {
+	case "all":
+		ui.setSelection(ui.Canvas.Sprites)
+	case "none":
+		ui.setSelection(nil)
+	case "duplicates":
+		ui.setSelection(ui.duplicates())
+	default:
+		return "", usageError("select")
+	}
+	return fmt.Sprintf("%d sprite(s) selected", len(ui.Selection())), nil
+}
+
+func consoleMove(ui *UI, args []string) (string, error) {
+	n, err := parseInts(args, 2, "move")
+	if err != nil {
+		return "", err
+	}
+	targets, err := consoleTargets(ui)
+	if err != nil {
+		return "", err
+	}
+	v := image.Pt(n[0], n[1])
+	if !ui.LockOrder {
+		ui.Canvas.ReorderAll(sprite.ReorderBringToFront, targets)
+	}
+	for _, sp := range targets {
+		sp.MoveBy(v)
+	}
+	return fmt.Sprintf("moved %d sprite(s) by %v", len(targets), v), nil
+}
+
+func consoleMoveTo(ui *UI, args []string) (string, error) {
+	n, err := parseInts(args, 2, "moveto")
+	if err != nil {
+		return "", err
+	}
+	targets, err := consoleTargets(ui)
+	if err != nil {
+		return "", err
+	}
+	p := image.Pt(n[0], n[1])
+	// keep the selection's layout, move its top left corner to p
+	r := targets[0].Rect()
+	for _, sp := range targets[1:] {
+		r = r.Union(sp.Rect())
+	}
+	if !ui.LockOrder {
+		ui.Canvas.ReorderAll(sprite.ReorderBringToFront, targets)
+	}
+	for _, sp := range targets {
+		sp.MoveBy(p.Sub(r.Min))
+	}
+	return fmt.Sprintf("moved %d sprite(s) to %v", len(targets), p), nil
+}
+
+func consoleReshape(ui *UI, args []string) (string, error) {
+	var n []int
+	var err error
+	if len(args) == 4 {
+		n, err = parseInts(args, 4, "reshape")
+	} else {
+		n, err = parseInts(args, 2, "reshape")
+	}
+	if err != nil {
+		return "", err
+	}
+	size := image.Pt(n[len(n)-2], n[len(n)-1])
+	if size.X < 1 || size.Y < 1 {
+		return "", fmt.Errorf("size must be at least 1x1, got %dx%d", size.X, size.Y)
+	}
+	targets, err := consoleTargets(ui)
+	if err != nil {
+		return "", err
+	}
+	for _, sp := range targets {
+		if len(n) == 4 {
+			sp.Reshape(image.Rect(n[0], n[1], n[0]+size.X, n[1]+size.Y))
+			continue
+		}
+		sp.Resize(size)
+	}
+	return fmt.Sprintf("reshaped %d sprite(s) to %dx%d", len(targets), size.X, size.Y), nil
+}
+
+func consoleOpacity(ui *UI, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", usageError("opacity")
+	}
+	o, err := strconv.ParseFloat(args[0], 64)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a number", args[0])
+	}
+	targets, err := consoleTargets(ui)
+	if err != nil {
+		return "", err
+	}
+	for _, sp := range targets {
+		sp.AddOpacity(o)
+	}
+	return fmt.Sprintf("opacity of %d sprite(s) offset by %v", len(targets), o), nil
+}
+
+func consoleReorder(ui *UI, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", usageError("reorder")
+	}
+	commands := map[string]sprite.ReorderCommand{
+		"front":    sprite.ReorderBringToFront,
+		"back":     sprite.ReorderSendToBack,
+		"forward":  sprite.ReorderBringForwards,
+		"backward": sprite.ReorderSendBackwards,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return "", usageError("reorder")
+	}
+	targets, err := consoleTargets(ui)
+	if err != nil {
+		return "", err
+	}
+	ui.Canvas.ReorderAll(command, targets)
+	return fmt.Sprintf("%v: %d sprite(s)", command, len(targets)), nil
+}
+
+func consoleDelete(ui *UI, args []string) (string, error) {
+	targets, err := consoleTargets(ui)
+	if err != nil {
+		return "", err
+	}
+	for _, sp := range targets {
+		ui.RemoveSprite(sp)
+	}
+	return fmt.Sprintf("deleted %d sprite(s)", len(targets)), nil
+}
+
+func consoleTargets(ui *UI) ([]*sprite.Sprite, error) {
+	targets := ui.Selection()
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("nothing selected")
+	}
+	return targets, nil
+}
+
+func usageError(name string) error {
+	return fmt.Errorf("usage: %s", consoleCommands[name].usage)
+}
+
+// parses exactly n integer arguments
+func parseInts(args []string, n int, name string) ([]int, error) {
+	if len(args) != n {
+		return nil, usageError(name)
+	}
+	ints := make([]int, n)
+	for i, a := range args {
+		v, err := strconv.Atoi(a)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", a)
+		}
+		ints[i] = v
+	}
+	return ints, nil
+}
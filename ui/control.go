@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+
+	"frame/control"
+	"frame/draw"
+)
+
+// handles a control socket request. decoding and encoding happen on the
+// calling goroutine, canvas access is handed to the game loop
+func (ui *UI) Handle(req control.Request) control.Response {
+	switch req.Command {
+	case control.CmdAddImage:
+		ims, err := decodeRequestImages(req, ui.decodeOptions())
+		if err != nil {
+			return control.ErrorResponse(err)
+		}
+		ims = fitAll(ims, ui.fitSize())
+		return ui.await(func(ui *UI) control.Response {
+			g := ui.addGroup(ims)
+			g.moveTo(image.Pt(req.X, req.Y))
+			if len(g.sprites) > 1 {
+				return control.Response{Message: fmt.Sprintf("added %d sprites", len(g.sprites))}
+			}
+			size := g.sprites[0].Image.Bounds().Size()
+			return control.Response{Message: fmt.Sprintf("added %dx%d sprite", size.X, size.Y)}
+		})
+	case control.CmdList:
+		return ui.await(func(ui *UI) control.Response {
+			sprites := []control.SpriteInfo{}
+			for i, sp := range ui.Canvas.Sprites {
+				r := sp.Rect()
+				sprites = append(sprites, control.SpriteInfo{
+					Index:   i,
+					X:       r.Min.X,
+					Y:       r.Min.Y,
+					Width:   r.Dx(),
+					Height:  r.Dy(),
+					Opacity: 1 + sp.OpacityOffset,
+				})
+			}
+			return control.Response{Sprites: sprites}
+		})
+	case control.CmdExport:
+		var img *image.RGBA
+		ui.await(func(ui *UI) control.Response {
+			ui.Canvas.DrawSprites()
+			img = draw.ToRGBA(ui.Canvas.Image())
+			return control.Response{}
+		})
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return control.ErrorResponse(err)
+		}
+		return control.Response{Data: buf.Bytes()}
+	case control.CmdClear:
+		return ui.await(func(ui *UI) control.Response {
+			ui.Canvas.ClearSprites()
+			return control.Response{Message: "cleared"}
+		})
+	}
+	return control.ErrorResponse(fmt.Errorf("unknown command %q", req.Command))
+}
+
+// runs f on the game loop and waits for its response
+func (ui *UI) await(f func(*UI) control.Response) control.Response {
+	done := make(chan control.Response, 1)
+	ui.Do(func(ui *UI) {
+		done <- f(ui)
+	})
+	return <-done
+}
+
+func decodeRequestImages(req control.Request, opts decodeOptions) ([]imported, error) {
+	if req.Path == "" {
+		return decodeImages(bytes.NewReader(req.Data), "control socket", opts)
+	}
+	return decodeFile(req.Path, opts)
+}
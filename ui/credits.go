@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"frame/meta"
+)
+
+// writes the sources of every sprite that shows on the canvas, as markdown
+// or, for a .json file, json
+type ExportCreditsOp struct {
+	prompt *PromptOp
+}
+
+func (op ExportCreditsOp) String() string { return "export sources manifest" }
+
+func (op *ExportCreditsOp) Update(ui *UI) (done bool, err error) {
+	if op.prompt == nil {
+		op.prompt = NewPrompt("save sources as (.md or .json)", "sources.md")
+		ui.addOperation(op.prompt)
+		return false, nil
+	}
+	if !op.prompt.done {
+		return false, nil
+	}
+	path := strings.TrimSpace(op.prompt.Text())
+	if path == "" {
+		return true, nil
+	}
+	credits := ui.Canvas.Credits(ui.Canvas.Image().Bounds())
+	if len(credits) == 0 {
+		return true, errors.New("no sources on the canvas")
+	}
+	if err := writeCredits(path, credits); err != nil {
+		return true, err
+	}
+	ui.notify("saved " + path)
+	return true, nil
+}
+
+func writeCredits(path string, credits []meta.Credit) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	write := meta.WriteMarkdown
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		write = meta.WriteJSON
+	}
+	if err := write(f, credits); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
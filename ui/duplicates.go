@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	imagedraw "image/draw"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"frame/sprite"
+)
+
+var duplicateClr = color.RGBA{255, 140, 0, 255} // orange
+
+// identical imports of the same size share one texture
+type textureKey struct {
+	hash string
+	size image.Point
+}
+
+// hashes the size and pixels of every frame
+func hashPixels(im imported) string {
+	frames := im.frames
+	if len(frames) == 0 {
+		frames = []image.Image{im.img}
+	}
+	h := sha256.New()
+	for _, f := range frames {
+		b := f.Bounds()
+		rgba, ok := f.(*image.RGBA)
+		if !ok || rgba.Stride != 4*b.Dx() {
+			rgba = image.NewRGBA(b)
+			imagedraw.Draw(rgba, b, f, b.Min, imagedraw.Src)
+		}
+		binary.Write(h, binary.LittleEndian, [2]int32{int32(b.Dx()), int32(b.Dy())})
+		h.Write(rgba.Pix[:4*b.Dx()*b.Dy()])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// the texture of a still import, shared with an earlier identical import
+// while a sprite still shows it. nil for animations
+func (ui *UI) texture(im imported) *ebiten.Image {
+	if len(im.frames) > 0 {
+		return nil
+	}
+	used := map[*ebiten.Image]bool{}
+	for _, sp := range ui.Canvas.Sprites {
+		used[sp.Image] = true
+	}
+	for k, tex := range ui.textures {
+		if !used[tex] {
+			delete(ui.textures, k)
+		}
+	}
+	key := textureKey{im.hash, im.img.Bounds().Size()}
+	if tex, ok := ui.textures[key]; ok {
+		return tex
+	}
+	tex := ebiten.NewImageFromImage(im.img)
+	if im.hash != "" {
+		if ui.textures == nil {
+			ui.textures = map[textureKey]*ebiten.Image{}
+		}
+		ui.textures[key] = tex
+	}
+	return tex
+}
+
+// first sprite imported with the same pixels, nil if none
+func (ui *UI) spriteWithHash(hash string) *sprite.Sprite {
+	if hash == "" {
+		return nil
+	}
+	for _, sp := range ui.Canvas.Sprites {
+		if sp.Hash == hash {
+			return sp
+		}
+	}
+	return nil
+}
+
+// sprites that were imported with the same pixels as another sprite
+func (ui *UI) duplicates() []*sprite.Sprite {
+	count := map[string]int{}
+	for _, sp := range ui.Canvas.Sprites {
+		if sp.Hash != "" {
+			count[sp.Hash]++
+		}
+	}
+	dups := []*sprite.Sprite{}
+	for _, sp := range ui.Canvas.Sprites {
+		if count[sp.Hash] > 1 {
+			dups = append(dups, sp)
+		}
+	}
+	return dups
+}
+
+func creditName(sp *sprite.Sprite) string {
+	if len(sp.Credits) == 0 {
+		return "a sprite"
+	}
+	return sp.Credits[0].Name
+}
+
+// selects and outlines sprites with identical source pixels until a click
+type SelectDuplicatesOp struct {
+	Targets []*sprite.Sprite
+}
+
+func (op SelectDuplicatesOp) String() string {
+	return fmt.Sprintf("%d duplicate(s) selected, click to continue", len(op.Targets))
+}
+
+func (op *SelectDuplicatesOp) Update(ui *UI) (done bool, err error) {
+	if op.Targets == nil {
+		op.Targets = ui.duplicates()
+		ui.setSelection(op.Targets)
+		if len(op.Targets) == 0 {
+			return true, errors.New("no duplicates")
+		}
+		return false, nil
+	}
+	return MouseJustPressed(ebiten.MouseButtonLeft), nil
+}
+
+func (op *SelectDuplicatesOp) Draw(dst *ebiten.Image) {
+	for _, sp := range op.Targets {
+		sp.Outline(dst, duplicateClr, 2, -1)
+	}
+}
@@ -0,0 +1,434 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"frame/draw"
+	"frame/export"
+	"frame/sprite"
+)
+
+var exportClr = color.RGBA{30, 144, 255, 255} // dodger blue
+
+type exportScope int
+
+const (
+	exportCanvas exportScope = iota
+	exportRegion
+	exportSelection
+)
+
+func (s exportScope) String() string {
+	switch s {
+	case exportRegion:
+		return "region"
+	case exportSelection:
+		return "selection"
+	default:
+		return "canvas"
+	}
+}
+
+// saves the sprites of the canvas, a dragged region or the selection to
+// an image file, without the window's overlays
+type ExportOp struct {
+	scope exportScope
+	// a pdf page instead of an image
+	pdf     bool
+	drag    MouseDrag
+	selOp   *SelectSpriteMultiOp
+	rect    image.Rectangle
+	sprites []*sprite.Sprite
+	prompt  *PromptOp
+}
+
+func (op ExportOp) String() string {
+	if op.scope == exportRegion && op.rect.Empty() {
+		return "export region: drag a region, click for the whole canvas"
+	}
+	if op.pdf {
+		return "export " + op.scope.String() + " as pdf"
+	}
+	return "export " + op.scope.String()
+}
+
+func (op *ExportOp) Update(ui *UI) (done bool, err error) {
+	if op.sprites == nil {
+		if !op.choose(ui) {
+			return false, nil
+		}
+		if len(op.sprites) == 0 || op.rect.Empty() {
+			return true, errors.New("nothing to export")
+		}
+	}
+	ext := ui.Settings.ExportFormat.Ext()
+	if op.pdf {
+		ext = ".pdf"
+	}
+	path, ok := ui.exportTarget(&op.prompt, "export as (.png, .jpg, .gif or .pdf)", "", ext)
+	if !ok {
+		return false, nil
+	}
+	if path == "" {
+		return true, nil
+	}
+	r := op.rect
+	scale := ui.Settings.ExportSize.Factor(r.Dx())
+	if strings.EqualFold(filepath.Ext(path), ".pdf") {
+		return true, ui.exportPDF(path, op.sprites, r, scale)
+	}
+	f, err := export.FormatOf(path)
+	if err != nil {
+		return true, err
+	}
+	layers := ui.layers(op.sprites, scale)
+	opts := export.Options{Quality: ui.Settings.JPEGQuality}
+	go func() {
+		img, err := export.Compose(layers, r, scale)
+		if err == nil {
+			err = writeImage(path, img, f, opts)
+		}
+		if err != nil {
+			ui.notifyError("export", err)
+			return
+		}
+		ui.notify("saved " + path)
+	}()
+	return true, nil
+}
+
+// saves the sprites in r as a pdf page, with images at the page's dpi
+func (ui *UI) exportPDF(path string, sprites []*sprite.Sprite, r image.Rectangle, scale float64) error {
+	page := ui.Settings.PDFPage
+	layers := ui.layers(sprites, page.Scale(r, scale))
+	go func() {
+		if err := writeFile(path, func(w io.Writer) error {
+			return export.WritePDF(w, layers, r, page, scale)
+		}); err != nil {
+			ui.notifyError("export pdf", err)
+			return
+		}
+		ui.notify("saved " + path)
+	}()
+	return nil
+}
+
+// picks the sprites and region to export, false until done
+func (op *ExportOp) choose(ui *UI) bool {
+	switch op.scope {
+	case exportRegion:
+		r, ok := ui.dragRegion(&op.drag)
+		if !ok {
+			return false
+		}
+		op.rect = r
+		op.sprites = ui.Canvas.Sprites
+	case exportSelection:
+		sel := ui.Selection()
+		if len(sel) == 0 {
+			if op.selOp == nil {
+				op.selOp = &SelectSpriteMultiOp{clr: exportClr}
+				ui.addOperation(op.selOp)
+			}
+			if !op.selOp.done {
+				return false
+			}
+			sel = op.selOp.Targets
+		}
+		op.sprites = sel
+		op.rect = image.Rectangle{}
+		for _, sp := range sel {
+			op.rect = op.rect.Union(sp.Rect())
+		}
+	default:
+		op.rect = ui.Canvas.Image().Bounds()
+		op.sprites = ui.Canvas.Sprites
+	}
+	if op.sprites == nil {
+		op.sprites = []*sprite.Sprite{}
+	}
+	return true
+}
+
+func (op *ExportOp) Draw(dst *ebiten.Image) {
+	if op.scope == exportRegion && op.drag.Started && op.rect.Empty() {
+		draw.StrokeRect(dst, op.drag.Rect(), exportClr, 1, 1)
+	}
+}
+
+// the sprites as export layers, back to front. for scales above 1 the full
+// resolution source pixels are used where a sprite has them. must run on
+// the game loop
+func (ui *UI) layers(sprites []*sprite.Sprite, scale float64) []export.Layer {
+	include := map[*sprite.Sprite]bool{}
+	for _, sp := range sprites {
+		include[sp] = true
+	}
+	layers := []export.Layer{}
+	for i := len(ui.Canvas.Sprites) - 1; i >= 0; i-- {
+		sp := ui.Canvas.Sprites[i]
+		if !include[sp] {
+			continue
+		}
+		layers = append(layers, export.Layer{
+			Name:    creditName(sp),
+			Image:   layerImage(sp, scale),
+			Rect:    sp.Rect(),
+			Opacity: math.Min(math.Max(0, 1+sp.OpacityOffset), 1),
+		})
+	}
+	return layers
+}
+
+func layerImage(sp *sprite.Sprite, scale float64) image.Image {
+	// cut sprites have holes the source does not
+	if src := sp.Source; scale > 1 && src != nil && src.Image != nil && !src.Cut {
+		return draw.SubImage(src.Image, src.Rect)
+	}
+	return draw.ToRGBA(sp.Image)
+}
+
+// "frame-20230501-120000"
+func exportName(t time.Time) string {
+	return "frame-" + t.Format("20060102-150405")
+}
+
+// a generated file name ending in ext in dir that is not taken yet
+func exportPath(dir string, ext string) string {
+	base := filepath.Join(dir, exportName(time.Now()))
+	path := base + ext
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return path
+		}
+		path = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+func writeImage(path string, img image.Image, f export.Format, opts export.Options) error {
+	return writeFile(path, func(w io.Writer) error {
+		return export.Encode(w, img, f, opts)
+	})
+}
+
+// saves the selected sprites, or all of them, to one png each in a folder,
+// with sprites.json giving their positions
+type ExportSpritesOp struct {
+	prompt *PromptOp
+}
+
+func (op ExportSpritesOp) String() string { return "export sprites" }
+
+func (op *ExportSpritesOp) Update(ui *UI) (done bool, err error) {
+	dir, ok := ui.exportTarget(&op.prompt, "export sprites to folder", "-sprites", "")
+	if !ok {
+		return false, nil
+	}
+	if dir == "" {
+		return true, nil
+	}
+	sprites := ui.Selection()
+	if len(sprites) == 0 {
+		sprites = ui.Canvas.Sprites
+	}
+	if len(sprites) == 0 {
+		return true, errors.New("nothing to export")
+	}
+	r := image.Rectangle{}
+	for _, sp := range sprites {
+		r = r.Union(sp.Rect())
+	}
+	scale := ui.Settings.ExportSize.Factor(r.Dx())
+	layers := ui.layers(sprites, scale)
+	go func() {
+		if err := export.WriteSprites(dir, layers, scale); err != nil {
+			ui.notifyError("export sprites", err)
+			return
+		}
+		ui.notify(fmt.Sprintf("saved %d sprite(s) to %s", len(layers), dir))
+	}()
+	return true, nil
+}
+
+// packs the selected sprites, or all of them, into one png, with a json
+// file giving each sprite's place in it
+type ExportAtlasOp struct {
+	prompt *PromptOp
+}
+
+func (op ExportAtlasOp) String() string { return "export atlas" }
+
+func (op *ExportAtlasOp) Update(ui *UI) (done bool, err error) {
+	path, ok := ui.exportTarget(&op.prompt, "export atlas as", "-atlas", ".png")
+	if !ok {
+		return false, nil
+	}
+	if path == "" {
+		return true, nil
+	}
+	if f, err := export.FormatOf(path); err != nil || f != export.PNG {
+		return true, errors.New("atlases are saved as png")
+	}
+	sprites := ui.Selection()
+	if len(sprites) == 0 {
+		sprites = ui.Canvas.Sprites
+	}
+	if len(sprites) == 0 {
+		return true, errors.New("nothing to export")
+	}
+	// a width is for the whole atlas, as packed at the canvas size
+	padding := ui.Settings.AtlasPadding
+	sizes := make([]image.Point, len(sprites))
+	for i, sp := range sprites {
+		sizes[i] = sp.Rect().Size()
+	}
+	_, packed := export.Pack(sizes, padding)
+	scale := ui.Settings.ExportSize.Factor(packed.X)
+	layers := ui.layers(sprites, scale)
+	go func() {
+		if err := export.WriteAtlas(path, layers, padding, scale); err != nil {
+			ui.notifyError("export atlas", err)
+			return
+		}
+		ui.notify("saved " + path)
+	}()
+	return true, nil
+}
+
+// where to save an export: a generated name ending in suffix and ext that
+// is not taken yet in the export folder when one is set, else asked for
+// with a generated name as the default. ext is added to a path without one.
+// ok is false while asking, an empty path cancels
+func (ui *UI) exportTarget(prompt **PromptOp, label, suffix, ext string) (path string, ok bool) {
+	if ui.Settings.ExportDir != "" {
+		return exportPath(ui.Settings.ExportDir, suffix+ext), true
+	}
+	if *prompt == nil {
+		*prompt = NewPrompt(label, exportName(time.Now())+suffix+ext)
+		ui.addOperation(*prompt)
+		return "", false
+	}
+	if !(*prompt).done {
+		return "", false
+	}
+	path = strings.TrimSpace((*prompt).Text())
+	if path != "" && ext != "" && filepath.Ext(path) == "" {
+		path += ext
+	}
+	return path, true
+}
+
+// saves every sprite as a layer of a file other painting programs open,
+// an OpenRaster file (which frame imports back) or a photoshop file
+type ExportLayersOp struct {
+	// ".ora" or ".psd"
+	ext    string
+	prompt *PromptOp
+}
+
+func (op ExportLayersOp) String() string {
+	if op.ext == ".psd" {
+		return "export psd"
+	}
+	return "export openraster"
+}
+
+func (op *ExportLayersOp) Update(ui *UI) (done bool, err error) {
+	path, ok := ui.exportTarget(&op.prompt, op.String()+" as", "", op.ext)
+	if !ok {
+		return false, nil
+	}
+	if path == "" {
+		return true, nil
+	}
+	if len(ui.Canvas.Sprites) == 0 {
+		return true, errors.New("nothing to export")
+	}
+	write := export.WriteORA
+	if op.ext == ".psd" {
+		write = export.WritePSD
+	}
+	r := ui.Canvas.Image().Bounds()
+	scale := ui.Settings.ExportSize.Factor(r.Dx())
+	layers := ui.layers(ui.Canvas.Sprites, scale)
+	go func() {
+		if err := writeFile(path, func(w io.Writer) error {
+			return write(w, layers, r, scale)
+		}); err != nil {
+			ui.notifyError(op.String(), err)
+			return
+		}
+		ui.notify("saved " + path)
+	}()
+	return true, nil
+}
+
+// saves the selected sprites, or all of them, as an svg or an html page
+// that keeps their layout, opacity and order
+type ExportWebOp struct {
+	// ".svg" or ".html"
+	ext    string
+	prompt *PromptOp
+}
+
+func (op ExportWebOp) String() string { return "export " + strings.TrimPrefix(op.ext, ".") }
+
+func (op *ExportWebOp) Update(ui *UI) (done bool, err error) {
+	path, ok := ui.exportTarget(&op.prompt, op.String()+" as", "", op.ext)
+	if !ok {
+		return false, nil
+	}
+	if path == "" {
+		return true, nil
+	}
+	sprites := ui.Selection()
+	if len(sprites) == 0 {
+		sprites = ui.Canvas.Sprites
+	}
+	if len(sprites) == 0 {
+		return true, errors.New("nothing to export")
+	}
+	r := image.Rectangle{}
+	for _, sp := range sprites {
+		r = r.Union(sp.Rect())
+	}
+	write := export.WriteSVG
+	if op.ext == ".html" {
+		write = export.WriteHTML
+	}
+	scale := ui.Settings.ExportSize.Factor(r.Dx())
+	layers := ui.layers(sprites, scale)
+	embed := ui.Settings.EmbedImages
+	go func() {
+		if err := write(path, layers, r, scale, embed); err != nil {
+			ui.notifyError(op.String(), err)
+			return
+		}
+		ui.notify("saved " + path)
+	}()
+	return true, nil
+}
+
+// creates the file at path and writes it with write
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
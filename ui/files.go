@@ -1,15 +1,31 @@
 package ui
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
+	"frame/anim"
+	"frame/canvas"
+	"frame/draw"
+	"frame/exif"
+	"frame/export"
+	"frame/meta"
 	"frame/sprite"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	"image/png"
+	"io"
 	"io/fs"
 	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
 
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
 	_ "golang.org/x/image/webp"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -17,67 +33,345 @@ import (
 	"golang.design/x/clipboard"
 )
 
-func (ui *UI) handleDroppedFiles() error {
-	if err := func() error {
-		ui.m.Lock()
-		defer ui.m.Unlock()
-		return ui.err
-	}(); err != nil {
-		return err
-	}
-
+func (ui *UI) handleDroppedFiles() {
 	if files := ebiten.DroppedFiles(); files != nil {
 		// log.Println(files)
-		go func() {
-			if err := fs.WalkDir(files, ".", func(path string, d fs.DirEntry, err error) error {
-				if err != nil {
-					return err
-				}
-
-				fi, err := d.Info()
-				if err != nil {
-					return err
-				}
-				log.Printf("Name: %s, Size: %d, IsDir: %t, ModTime: %v", fi.Name(), fi.Size(), fi.IsDir(), fi.ModTime())
-
-				f, err := files.Open(path)
-				if err != nil {
-					return err
-				}
-
-				defer func() {
-					_ = f.Close()
-				}()
-
-				if fi.IsDir() {
-					return nil
-				}
-
-				img, _, err := image.Decode(f)
-				if err != nil {
-					return err
-				}
-
-				ui.m.Lock()
-				ui.Canvas.AddImage(img)
-				ui.m.Unlock()
-
-				return nil
-			}); err != nil {
-				ui.m.Lock()
-				if ui.err == nil {
-					ui.err = err
-				}
-				ui.m.Unlock()
-			}
-		}()
+		// cascade from where the files were dropped so each stays visible
+		drop := MousePos()
+		if !drop.In(ui.Canvas.Image().Bounds()) {
+			drop = image.Point{}
+		}
+		ui.startImport(&importJob{
+			list: func() ([]importTask, error) {
+				return fsTasks(files, "")
+			},
+			place: func(i int, s *sprite.Sprite) {
+				s.Pos = canvas.CascadePos(drop, i)
+			},
+			onError: func(ui *UI, name string, err error) bool {
+				ui.notifyError("import "+name, err)
+				return true
+			},
+		})
+	}
+}
+
+// decoded image and the file it came from
+type imported struct {
+	img image.Image
+	src *sprite.Source
+	// every frame of an animation, img is the first. empty for stills
+	frames []image.Image
+	delays []time.Duration
+	// the sprite's attribution, the import time is set when it is added
+	credit meta.Credit
+	// of the decoded pixels, before fitting
+	hash string
+	// set for a layer of a layered file
+	layer *layerInfo
+}
+
+// where a layer sits in its file
+type layerInfo struct {
+	name    string
+	pos     image.Point
+	opacity float64
+}
+
+// scales the image down to fit within max, keeping the original pixels of
+// a still image in the source for export. a zero max keeps the size
+func (im imported) fit(max image.Point) imported {
+	b := im.img.Bounds()
+	if max.X < 1 || max.Y < 1 || (b.Dx() <= max.X && b.Dy() <= max.Y) {
+		return im
+	}
+	scale := math.Min(float64(max.X)/float64(b.Dx()), float64(max.Y)/float64(b.Dy()))
+	size := image.Pt(int(float64(b.Dx())*scale), int(float64(b.Dy())*scale))
+	src := &sprite.Source{Rect: b}
+	if im.src != nil {
+		c := *im.src
+		src = &c
+	}
+	scaled := imported{img: draw.Scale(im.img, size), src: src, delays: im.delays, credit: im.credit, hash: im.hash, layer: im.layer}
+	if len(im.frames) == 0 {
+		src.Image = im.img
+		return scaled
+	}
+	for _, f := range im.frames {
+		scaled.frames = append(scaled.frames, draw.Scale(f, size))
+	}
+	scaled.img = scaled.frames[0]
+	return scaled
+}
+
+// tex is the image of a still sprite
+func newSprite(im imported, tex *ebiten.Image) *sprite.Sprite {
+	credit := im.credit
+	credit.Imported = time.Now()
+	s := &sprite.Sprite{
+		Image:   tex,
+		Source:  im.src,
+		Credits: []meta.Credit{credit},
+		Hash:    im.hash,
+	}
+	if im.layer != nil {
+		s.OpacityOffset = im.layer.opacity - 1
+	}
+	if len(im.frames) > 0 {
+		for _, f := range im.frames {
+			s.Frames = append(s.Frames, ebiten.NewImageFromImage(f))
+		}
+		s.Delays = im.delays
+		s.Image = s.Frames[0]
+	}
+	return s
+}
+
+// adds an imported image in front of the other sprites. exact duplicates
+// of a sprite on the canvas are reported and share its texture
+func (ui *UI) addImported(im imported) *sprite.Sprite {
+	if dup := ui.spriteWithHash(im.hash); dup != nil {
+		ui.notify(fmt.Sprintf("%s is a duplicate of %s", im.credit.Name, creditName(dup)))
+	}
+	s := newSprite(im, ui.texture(im))
+	ui.Canvas.AddSprite(s)
+	return s
+}
+
+// decodes an image with the registered decoders
+func decodeImage(r io.Reader) (image.Image, error) {
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// how files are decoded
+type decodeOptions struct {
+	// every frame of an animated gif instead of the first
+	gifFrames bool
+	// apply the exif orientation of jpegs
+	orient bool
+}
+
+// decodes an image. animated gifs and pngs keep their frames, or with
+// gifFrames set a gif becomes one image per frame. jpegs are turned upright
+// with orient set. an OpenRaster file gives its layers, back to front.
+// name credits where the image came from
+func decodeImages(r io.Reader, name string, opts decodeOptions) ([]imported, error) {
+	ims, err := decode(r, opts)
+	for i := range ims {
+		b := ims[i].img.Bounds()
+		ims[i].credit.Name = name
+		if l := ims[i].layer; l != nil && l.name != "" {
+			ims[i].credit.Name += ": " + l.name
+		}
+		ims[i].credit.Width, ims[i].credit.Height = b.Dx(), b.Dy()
+		ims[i].hash = hashPixels(ims[i])
+	}
+	return ims, err
+}
+
+func decode(r io.Reader, opts decodeOptions) ([]imported, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(8)
+	var frames []*image.RGBA
+	var delays []time.Duration
+	var err error
+	switch {
+	case bytes.HasPrefix(magic, []byte("GIF8")):
+		frames, delays, err = anim.DecodeGIF(br)
+	case bytes.HasPrefix(magic, []byte("\x89PNG")):
+		var data []byte
+		if data, err = io.ReadAll(br); err != nil {
+			return nil, err
+		}
+		if !anim.IsAPNG(data) {
+			return decodeStill(bytes.NewReader(data))
+		}
+		frames, delays, err = anim.DecodeAPNG(bytes.NewReader(data))
+	case bytes.HasPrefix(magic, []byte{0xff, 0xd8}):
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		img, err := decodeImage(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		info, err := exif.Read(bytes.NewReader(data))
+		if err != nil {
+			log.Println("exif:", err)
+		}
+		if opts.orient {
+			img = exif.Orient(img, info.Orientation)
+		}
+		im := imported{img: img}
+		im.credit.Artist = info.Artist
+		im.credit.Copyright = info.Copyright
+		return []imported{im}, nil
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		if !export.IsORA(data) {
+			return decodeStill(bytes.NewReader(data))
+		}
+		return decodeORA(data)
+	default:
+		return decodeStill(br)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, errors.New("no frames")
+	}
+	if opts.gifFrames && bytes.HasPrefix(magic, []byte("GIF8")) {
+		ims := make([]imported, len(frames))
+		for i, f := range frames {
+			ims[i].img = f
+		}
+		return ims, nil
+	}
+	im := imported{img: frames[0]}
+	if len(frames) > 1 {
+		for _, f := range frames {
+			im.frames = append(im.frames, f)
+		}
+		im.delays = delays
+	}
+	return []imported{im}, nil
+}
+
+func decodeStill(r io.Reader) ([]imported, error) {
+	img, err := decodeImage(r)
+	if err != nil {
+		return nil, err
+	}
+	return []imported{{img: img}}, nil
+}
+
+func decodeORA(data []byte) ([]imported, error) {
+	layers, _, err := export.ReadORA(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(layers) == 0 {
+		return nil, errors.New("no layers")
+	}
+	ims := make([]imported, len(layers))
+	for i, l := range layers {
+		ims[i] = imported{
+			img:   l.Image,
+			layer: &layerInfo{name: l.Name, pos: l.Rect.Min, opacity: l.Opacity},
+		}
+	}
+	return ims, nil
+}
+
+// the sprites added for one file
+type importGroup struct {
+	sprites []*sprite.Sprite
+	// positions of the layers of a layered file, nil for other files
+	layers []image.Point
+}
+
+func (g *importGroup) add(s *sprite.Sprite, im imported) {
+	g.sprites = append(g.sprites, s)
+	if im.layer != nil {
+		g.layers = append(g.layers, im.layer.pos)
+	}
+}
+
+// moves the first sprite to pos and lays the rest out after it
+func (g importGroup) moveTo(pos image.Point) {
+	if len(g.sprites) == 0 {
+		return
+	}
+	g.sprites[0].Pos = pos
+	g.layout()
+}
+
+// lays the sprites after the first out relative to it: layers where they
+// were in their file, anything else in a row to its right
+func (g importGroup) layout() {
+	if len(g.layers) != len(g.sprites) {
+		strip(g.sprites)
+		return
+	}
+	first := g.sprites[0]
+	for i := 1; i < len(g.sprites); i++ {
+		g.sprites[i].Pos = first.Pos.Add(g.layers[i].Sub(g.layers[0]))
+	}
+}
+
+// lays the sprites after the first out in a row to its right
+func strip(sprites []*sprite.Sprite) {
+	for i := 1; i < len(sprites); i++ {
+		prev := sprites[i-1]
+		sprites[i].Pos = prev.Pos.Add(image.Pt(prev.Image.Bounds().Dx(), 0))
+	}
+}
+
+// decodes the image file at path, linked to its source
+func decodeFile(path string, opts decodeOptions) ([]imported, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	ims, err := decodeImages(f, filepath.Base(path), opts)
+	if err != nil {
+		return nil, err
+	}
+	linkFile(ims, path, fi)
+	return ims, nil
+}
+
+// fits the images of one file into max. layers keep their size so they
+// still line up
+func fitAll(ims []imported, max image.Point) []imported {
+	for i := range ims {
+		if ims[i].layer == nil {
+			ims[i] = ims[i].fit(max)
+		}
+	}
+	return ims
+}
+
+// adds the images of one file in front of the other sprites
+func (ui *UI) addGroup(ims []imported) importGroup {
+	g := importGroup{}
+	for _, im := range ims {
+		g.add(ui.addImported(im), im)
+	}
+	return g
+}
+
+// links images decoded from the file at path to it
+func linkFile(ims []imported, path string, fi fs.FileInfo) {
+	for i, im := range ims {
+		ims[i].src = &sprite.Source{
+			Path:    path,
+			ModTime: fi.ModTime(),
+			Rect:    im.img.Bounds(),
+			// reloading brings back the first frame only
+			Detached: len(ims) > 1 || len(im.frames) > 0,
+		}
+		ims[i].credit.Path = path
 	}
-	return nil
 }
 
 var clipboardEnabled bool
 
 func copyClipboard(img *ebiten.Image) error {
+	if !clipboardEnabled {
+		return errors.New("no clipboard")
+	}
 	i := img.SubImage(img.Bounds())
 	if i == nil {
 		return nil
@@ -91,31 +385,27 @@ func copyClipboard(img *ebiten.Image) error {
 	return nil
 }
 
-func handlePaste() (*sprite.Sprite, error) {
+// adds the clipboard images, an empty group if there are none
+func (ui *UI) handlePaste() (importGroup, error) {
 	if !clipboardEnabled {
-		return nil, nil
+		return importGroup{}, nil
 	}
 	b := clipboard.Read(clipboard.FmtImage)
 	if b == nil {
-		return nil, nil
+		return importGroup{}, nil
 	}
-	img, _, err := image.Decode(bytes.NewReader(b))
+	ims, err := decodeImages(bytes.NewReader(b), "clipboard", ui.decodeOptions())
 	if err != nil {
-		return nil, err
-	}
-	if img == nil {
-		return nil, nil
-	}
-	s := &sprite.Sprite{
-		Image: ebiten.NewImageFromImage(img),
+		return importGroup{}, err
 	}
-	return s, nil
+	return ui.addGroup(fitAll(ims, ui.fitSize())), nil
 }
 
 func init() {
 	err := clipboard.Init()
 	if err != nil {
 		log.Println("no clipboard", err)
+		return
 	}
 	clipboardEnabled = true
 }
@@ -0,0 +1,276 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"frame/canvas"
+	"frame/sprite"
+)
+
+// at most this many files are decoded at once
+var importWorkers = 4
+
+func init() {
+	if n := runtime.NumCPU(); n < importWorkers {
+		importWorkers = n
+	}
+}
+
+// one file to decode, into one image or a strip of frames
+type importTask struct {
+	name   string
+	decode func(opts decodeOptions) ([]imported, error)
+}
+
+// files decoded in parallel and added in their original order
+type importJob struct {
+	// lists the files to import, runs on the job's goroutine
+	list func() ([]importTask, error)
+	// positions the first sprite of the i-th file when it is added, the
+	// frames after it follow in a strip and layers where they were
+	place func(i int, s *sprite.Sprite)
+	// called with the sprites added for each file once the job ends
+	finish func(groups []importGroup)
+	// called for a file that could not be imported, false cancels the job
+	onError func(ui *UI, name string, err error) bool
+
+	fit   image.Point
+	opts  decodeOptions
+	total int
+	// files decoded and added or failed
+	done   int
+	groups []importGroup
+	cancel context.CancelFunc
+}
+
+type importResult struct {
+	ims []imported
+	err error
+}
+
+// starts importing in the background. sprites are added on the game loop
+func (ui *UI) startImport(job *importJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job.cancel = cancel
+	job.fit = ui.fitSize()
+	job.opts = ui.decodeOptions()
+	ui.imports = append(ui.imports, job)
+	go job.run(ctx, ui)
+}
+
+func (job *importJob) run(ctx context.Context, ui *UI) {
+	defer ui.Do(func(ui *UI) {
+		ui.endImport(job)
+	})
+	tasks, err := job.list()
+	if err != nil {
+		ui.notifyError("import", err)
+		return
+	}
+	ui.Do(func(ui *UI) {
+		job.total = len(tasks)
+	})
+
+	results := make([]chan importResult, len(tasks))
+	for i := range results {
+		results[i] = make(chan importResult, 1)
+	}
+	work := make(chan int)
+	go func() {
+		defer close(work)
+		for i := range tasks {
+			select {
+			case work <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for w := 0; w < importWorkers; w++ {
+		go func() {
+			for i := range work {
+				ims, err := tasks[i].decode(job.opts)
+				results[i] <- importResult{fitAll(ims, job.fit), err}
+			}
+		}()
+	}
+
+	for i := range tasks {
+		task := tasks[i]
+		var r importResult
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			return
+		}
+		ui.Do(func(ui *UI) {
+			if ctx.Err() != nil {
+				return
+			}
+			job.done++
+			if r.err != nil {
+				if !job.onError(ui, task.name, r.err) {
+					job.cancel()
+				}
+				return
+			}
+			group := ui.addGroup(r.ims)
+			if len(group.sprites) == 0 {
+				return
+			}
+			if job.place != nil {
+				job.place(len(job.groups), group.sprites[0])
+			}
+			group.layout()
+			job.groups = append(job.groups, group)
+		})
+	}
+}
+
+func (ui *UI) endImport(job *importJob) {
+	job.cancel()
+	for i, j := range ui.imports {
+		if j == job {
+			ui.imports = append(ui.imports[:i], ui.imports[i+1:]...)
+			break
+		}
+	}
+	if job.finish != nil {
+		job.finish(job.groups)
+	}
+}
+
+// stops all imports, sprites already added stay
+func (ui *UI) cancelImports() {
+	for _, job := range ui.imports {
+		job.cancel()
+	}
+}
+
+// "importing 12/40", empty if nothing is being imported
+func (ui *UI) importStatus() string {
+	if len(ui.imports) == 0 {
+		return ""
+	}
+	done, total := 0, 0
+	for _, job := range ui.imports {
+		done += job.done
+		total += job.total
+	}
+	return fmt.Sprintf("importing %d/%d (esc cancels)", done, total)
+}
+
+// every file under fsys, in walk order. names are joined to prefix
+func fsTasks(fsys fs.FS, prefix string) ([]importTask, error) {
+	tasks := []importTask{}
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		tasks = append(tasks, importTask{
+			name: filepath.Join(prefix, path),
+			decode: func(opts decodeOptions) ([]imported, error) {
+				return decodeFS(fsys, path, opts)
+			},
+		})
+		return nil
+	})
+	return tasks, err
+}
+
+// decodes a file of fsys, linked to its source if it is on disk
+func decodeFS(fsys fs.FS, path string, opts decodeOptions) ([]imported, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Name: %s, Size: %d, ModTime: %v", fi.Name(), fi.Size(), fi.ModTime())
+
+	ims, err := decodeImages(f, fi.Name(), opts)
+	if err != nil {
+		return nil, err
+	}
+	if osf, ok := f.(*os.File); ok {
+		linkFile(ims, osf.Name(), fi)
+	}
+	return ims, nil
+}
+
+// loads images from paths, in order, in the background: files, folders,
+// and - for stdin. the sprites are arranged by layout once all are added
+func (ui *UI) Open(paths []string, layout canvas.Layout) {
+	ui.startImport(&importJob{
+		list: func() ([]importTask, error) {
+			tasks := []importTask{}
+			for _, path := range paths {
+				t, err := pathTasks(path)
+				if err != nil {
+					ui.notifyError("open", err)
+					continue
+				}
+				tasks = append(tasks, t...)
+			}
+			return tasks, nil
+		},
+		finish: func(groups []importGroup) {
+			first := make([]*sprite.Sprite, len(groups))
+			for i, g := range groups {
+				first[i] = g.sprites[0]
+			}
+			ui.Canvas.Arrange(first, layout, image.Point{})
+			for _, g := range groups {
+				g.layout()
+			}
+		},
+		onError: func(ui *UI, name string, err error) bool {
+			ui.notifyError("open "+name, err)
+			return true
+		},
+	})
+}
+
+// files named by one command line argument
+func pathTasks(path string) ([]importTask, error) {
+	if path == "-" {
+		return []importTask{{
+			name: "stdin",
+			decode: func(opts decodeOptions) ([]imported, error) {
+				return decodeImages(os.Stdin, "stdin", opts)
+			},
+		}}, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return fsTasks(os.DirFS(abs), path)
+	}
+	return []importTask{{
+		name: path,
+		decode: func(opts decodeOptions) ([]imported, error) {
+			return decodeFS(os.DirFS(filepath.Dir(abs)), filepath.Base(abs), opts)
+		},
+	}}, nil
+}
@@ -93,3 +93,33 @@ func CancelInput() bool {
 	}
 	return false
 }
+
+// single line of typed text
+type TextInput struct {
+	Text string
+}
+
+// returns true when enter is pressed
+func (t *TextInput) Update() (submitted bool) {
+	for _, r := range ebiten.AppendInputChars(nil) {
+		t.Text += string(r)
+	}
+	if KeyRepeated(ebiten.KeyBackspace) && len(t.Text) > 0 {
+		runes := []rune(t.Text)
+		t.Text = string(runes[:len(runes)-1])
+	}
+	return inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadEnter)
+}
+
+// true on the first press of key and then repeatedly while held
+func KeyRepeated(key ebiten.Key) bool {
+	const (
+		delay    = 30
+		interval = 3
+	)
+	d := inpututil.KeyPressDuration(key)
+	if d == 1 {
+		return true
+	}
+	return d >= delay && (d-delay)%interval == 0
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"frame/draw"
+)
+
+var (
+	linkInterval = time.Second
+	linkClr      = color.RGBA{255, 0, 0, 255} // red
+)
+
+type sourceUpdate struct {
+	img     image.Image
+	modTime time.Time
+}
+
+// reloads sprites whose source files changed. files are checked and decoded
+// on another goroutine, sprites are updated on the game loop
+func (ui *UI) checkLinks() {
+	if ui.linkBusy || time.Since(ui.linkChecked) < linkInterval {
+		return
+	}
+	ui.linkChecked = time.Now()
+	// oldest known version of each file
+	links := map[string]time.Time{}
+	for _, sp := range ui.Canvas.Sprites {
+		if sp.Source == nil || sp.Source.Path == "" {
+			continue
+		}
+		if t, ok := links[sp.Source.Path]; !ok || sp.Source.ModTime.Before(t) {
+			links[sp.Source.Path] = sp.Source.ModTime
+		}
+	}
+	if len(links) == 0 {
+		return
+	}
+	ui.linkBusy = true
+	go func() {
+		updates := map[string]sourceUpdate{}
+		for path, t := range links {
+			fi, err := os.Stat(path)
+			if err != nil || !fi.ModTime().After(t) {
+				continue
+			}
+			// reloading brings back the first image only
+			var img image.Image
+			ims, err := decodeFile(path, ui.decodeOptions())
+			if err != nil {
+				ui.notifyError("reload "+path, err)
+			} else {
+				img = ims[0].img
+			}
+			updates[path] = sourceUpdate{img, fi.ModTime()}
+		}
+		ui.Do(func(ui *UI) {
+			ui.linkBusy = false
+			for _, sp := range ui.Canvas.Sprites {
+				if sp.Source == nil {
+					continue
+				}
+				u, ok := updates[sp.Source.Path]
+				if !ok || !u.modTime.After(sp.Source.ModTime) {
+					continue
+				}
+				if !sp.Reload(u.img, u.modTime) {
+					ui.notify(fmt.Sprintf("%s changed, sprite kept", filepath.Base(sp.Source.Path)))
+				}
+			}
+		})
+	}()
+}
+
+// marks sprites whose source changed but could not be reloaded
+func (ui *UI) drawSourceMarkers(dst *ebiten.Image) {
+	for _, sp := range ui.Canvas.Sprites {
+		if sp.Source == nil || !sp.Source.Changed {
+			continue
+		}
+		sp.Outline(dst, linkClr, 2, -2)
+		if ui.sourceMarker == nil {
+			ui.sourceMarker = draw.TextLineImage("source changed", draw.Font, menuItemHeight, menuPadding, color.White, linkClr)
+		}
+		draw.DrawImage(dst, ui.sourceMarker, sp.Pos, 1)
+	}
+}
@@ -19,6 +19,17 @@ var (
 )
 
 func MainMenu(ui *UI) *Menu {
+	options := append([]*MenuOption{RepeatMenuOption(ui.lastOp)}, mainMenuOptions()...)
+	p := true
+	return &Menu{
+		options:      options,
+		mouseButton:  ebiten.MouseButtonRight,
+		startPressed: &p,
+	}
+}
+
+// options of the main menu, without the repeat option
+func mainMenuOptions() []*MenuOption {
 	reorderMenuOps := []*MenuOption{
 		{text: "bring to front", operation: &ReorderOp{command: sprite.ReorderBringToFront}},
 		{text: "send to back", operation: &ReorderOp{command: sprite.ReorderSendToBack}},
@@ -30,11 +41,38 @@ func MainMenu(ui *UI) *Menu {
 		{text: "copy to clipboard", operation: &CBCopyOp{}},
 		{text: "paste from clipboard", operation: &CBPasteOp{}},
 		{text: "(un)lock order", operation: &LockOrderOp{}},
+		{text: "select duplicates", operation: &SelectDuplicatesOp{}},
+		{text: "(un)watch folder", operation: &WatchFolderOp{}},
+		{text: "toggle auto-fit imports", operation: &FitImportsOp{}},
+		{text: "toggle gif frames as strip", operation: &GIFFramesOp{}},
+		{text: "toggle exif orientation", operation: &ExifOrientationOp{}},
+		{text: "(stop) record timelapse", operation: &TimelapseOp{}},
 		{text: "delete all", operation: &DeleteAllOp{}},
 	}
 	utilityMenu := NewMenu(utilityMenuOps, ebiten.MouseButtonLeft)
-	options := []*MenuOption{
-		RepeatMenuOption(ui.lastOp),
+	exportMenuOps := []*MenuOption{
+		{text: "canvas", operation: &ExportOp{scope: exportCanvas}},
+		{text: "region", operation: &ExportOp{scope: exportRegion}},
+		{text: "selection", operation: &ExportOp{scope: exportSelection}},
+		{text: "pdf canvas", operation: &ExportOp{scope: exportCanvas, pdf: true}},
+		{text: "pdf region", operation: &ExportOp{scope: exportRegion, pdf: true}},
+		{text: "sprites", operation: &ExportSpritesOp{}},
+		{text: "atlas", operation: &ExportAtlasOp{}},
+		{text: "openraster", operation: &ExportLayersOp{ext: ".ora"}},
+		{text: "psd", operation: &ExportLayersOp{ext: ".psd"}},
+		{text: "svg", operation: &ExportWebOp{ext: ".svg"}},
+		{text: "html", operation: &ExportWebOp{ext: ".html"}},
+		{text: "animation", operation: &ExportAnimationOp{}},
+		{text: "timelapse", operation: &ExportTimelapseOp{}},
+		{text: "sources manifest", operation: &ExportCreditsOp{}},
+		{text: "set size", operation: &ExportSizeOp{}},
+		{text: "set atlas padding", operation: &AtlasPaddingOp{}},
+		{text: "toggle embedded web images", operation: &EmbedImagesOp{}},
+		{text: "set pdf page", operation: &PDFPageOp{}},
+		{text: "set timelapse timing", operation: &TimelapseTimingOp{}},
+	}
+	exportMenu := NewMenu(exportMenuOps, ebiten.MouseButtonLeft)
+	return []*MenuOption{
 		{text: "move", operation: &MoveOp{}},
 		{text: "copy", operation: &CopyOp{}},
 		{text: "crop", operation: &CropOp{}},
@@ -44,14 +82,9 @@ func MainMenu(ui *UI) *Menu {
 		{text: "opacity", operation: &OpacityOp{}},
 		{text: "delete", operation: &DeleteOp{}},
 		{text: "reorder", operation: reorderMenu},
+		{text: "export", operation: exportMenu},
 		{text: "util", operation: utilityMenu},
 	}
-	p := true
-	return &Menu{
-		options:      options,
-		mouseButton:  ebiten.MouseButtonRight,
-		startPressed: &p,
-	}
 }
 
 func RepeatMenuOption(op Operation) *MenuOption {
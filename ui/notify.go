@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"frame/draw"
+)
+
+var (
+	toastInfoLife  = 4 * time.Second
+	toastErrorLife = 10 * time.Second
+	toastMax       = 5
+	toastMargin    = 8
+	toastInfoBg    = color.RGBA{60, 60, 60, 230}
+	toastErrorBg   = color.RGBA{180, 30, 30, 230}
+)
+
+// short message shown in the bottom right corner, click to dismiss
+type toast struct {
+	text    string
+	isErr   bool
+	expires time.Time
+	img     *ebiten.Image
+	rect    image.Rectangle
+}
+
+// logs err with what was being done and shows it as a toast. safe to call
+// from any goroutine
+func (ui *UI) notifyError(context string, err error) {
+	if err == nil {
+		return
+	}
+	msg := err.Error()
+	if context != "" {
+		msg = fmt.Sprintf("%s: %v", context, err)
+	}
+	log.Println(msg)
+	ui.addToast(msg, true)
+}
+
+// logs msg and shows it as a toast. safe to call from any goroutine
+func (ui *UI) notify(msg string) {
+	log.Println(msg)
+	ui.addToast(msg, false)
+}
+
+func (ui *UI) addToast(msg string, isErr bool) {
+	life := toastInfoLife
+	if isErr {
+		life = toastErrorLife
+	}
+	ui.Do(func(ui *UI) {
+		ui.toasts = append(ui.toasts, &toast{
+			text:    msg,
+			isErr:   isErr,
+			expires: time.Now().Add(life),
+		})
+		if len(ui.toasts) > toastMax {
+			ui.toasts = ui.toasts[len(ui.toasts)-toastMax:]
+		}
+	})
+}
+
+// drops expired toasts. returns true if a click dismissed one, the click
+// should not start an operation then
+func (ui *UI) updateToasts() (clicked bool) {
+	now := time.Now()
+	toasts := ui.toasts[:0]
+	for _, t := range ui.toasts {
+		if now.After(t.expires) {
+			continue
+		}
+		if MouseJustPressed(ebiten.MouseButtonLeft) && MousePos().In(t.rect) {
+			clicked = true
+			continue
+		}
+		toasts = append(toasts, t)
+	}
+	ui.toasts = toasts
+	return clicked
+}
+
+// newest toast at the bottom
+func (ui *UI) drawToasts(dst *ebiten.Image) {
+	b := dst.Bounds()
+	y := b.Max.Y - toastMargin
+	for i := len(ui.toasts) - 1; i >= 0; i-- {
+		t := ui.toasts[i]
+		if t.img == nil {
+			bg := toastInfoBg
+			if t.isErr {
+				bg = toastErrorBg
+			}
+			t.img = draw.TextLineImage(t.text, draw.Font, menuItemHeight, menuPadding, color.White, bg)
+		}
+		size := t.img.Bounds().Size()
+		y -= size.Y
+		t.rect = image.Rectangle{Min: image.Pt(b.Max.X-toastMargin-size.X, y), Max: image.Pt(b.Max.X-toastMargin, y+size.Y)}
+		draw.DrawImage(dst, t.img, t.rect.Min, 1)
+		y -= toastMargin / 2
+	}
+}
@@ -49,6 +49,12 @@ func CopyOperation(op Operation) Operation {
 		return &CopyOp{}
 	case *CutOp:
 		return &CutOp{}
+	case *ExportOp:
+		return &ExportOp{scope: op.scope, pdf: op.pdf}
+	case *ExportLayersOp:
+		return &ExportLayersOp{ext: op.ext}
+	case *ExportWebOp:
+		return &ExportWebOp{ext: op.ext}
 	}
 	return nil
 }
@@ -85,10 +91,12 @@ func (op *SelectSpriteMultiOp) Update(ui *UI) (done bool, err error) {
 		}
 		op.Targets = append(op.Targets, sp)
 		op.done = true
+		ui.setSelection(op.Targets)
 		return true, nil
 	}
 	if op.drag.Released {
 		op.done = true
+		ui.setSelection(op.Targets)
 		return true, nil
 	}
 	if !op.moved {
@@ -145,6 +153,9 @@ func (op *SelectSpriteRectOp) Update(ui *UI) (done bool, err error) {
 	}
 	op.target = ui.Canvas.SpriteAt(op.selDrag.End)
 	op.isSprite = true
+	if op.target != nil {
+		ui.setSelection([]*sprite.Sprite{op.target})
+	}
 	return true, nil
 }
 
@@ -177,6 +188,9 @@ func (op *SelectSpriteOp) Update(ui *UI) (done bool, err error) {
 	op.target = ui.Canvas.SpriteAt(MousePos())
 	if MouseJustPressed(ebiten.MouseButtonLeft) {
 		op.done = true
+		if op.target != nil {
+			ui.setSelection([]*sprite.Sprite{op.target})
+		}
 		return true, nil
 	}
 	return false, nil
@@ -191,6 +205,49 @@ func (op *SelectSpriteOp) Draw(dst *ebiten.Image) {
 	}
 }
 
+// asks for a line of text, enter confirms
+type PromptOp struct {
+	label string
+	input TextInput
+	done  bool
+
+	img    *ebiten.Image
+	imgTxt string
+}
+
+func NewPrompt(label, text string) *PromptOp {
+	return &PromptOp{
+		label: label,
+		input: TextInput{Text: text},
+	}
+}
+
+func (op PromptOp) String() string { return op.label }
+
+func (op *PromptOp) Text() string {
+	return op.input.Text
+}
+
+func (op *PromptOp) Update(ui *UI) (done bool, err error) {
+	if op.input.Update() {
+		op.done = true
+		return true, nil
+	}
+	return false, nil
+}
+
+func (op *PromptOp) Draw(dst *ebiten.Image) {
+	txt := op.label + ": " + op.input.Text + "_"
+	if op.img == nil || txt != op.imgTxt {
+		op.img = draw.TextLineImage(txt, draw.Font, menuItemHeight, menuPadding, menuFg, menuBg)
+		op.imgTxt = txt
+	}
+	im := op.img
+	pos := image.Pt((dst.Bounds().Dx()-im.Bounds().Dx())/2, paletteTop)
+	draw.DrawImage(dst, im, pos, 1)
+	draw.StrokeRect(dst, im.Bounds().Add(pos), menuPaddingClr, 2, 2)
+}
+
 type MoveOp struct {
 	selOp   *SelectSpriteMultiOp
 	drag    MouseDrag
@@ -258,6 +315,7 @@ func (op *DragOp) Update(ui *UI) (done bool, err error) {
 				return true, nil
 			}
 			op.Targets = append(op.Targets, s)
+			ui.setSelection(op.Targets)
 		}
 		// return false, nil
 	}
@@ -300,6 +358,7 @@ func (op *CropOp) Update(ui *UI) (done bool, err error) {
 	if !op.drag.Update() {
 		return false, nil
 	}
+	cropped := []*sprite.Sprite{}
 	for _, sp := range op.Targets {
 		i := ui.Canvas.Sprites.IndexOf(sp)
 		s := sp.Crop(op.drag.Rect())
@@ -308,7 +367,9 @@ func (op *CropOp) Update(ui *UI) (done bool, err error) {
 			continue
 		}
 		ui.Canvas.Sprites[i] = s
+		cropped = append(cropped, s)
 	}
+	ui.setSelection(cropped)
 	return true, nil
 }
 
@@ -397,15 +458,12 @@ func (op *FlattenOp) Update(ui *UI) (done bool, err error) {
 		op.clr = color.RGBA{50, 205, 50, 255} // lime green
 	}
 	if op.rect.Empty() {
-		if !op.drag.Update() {
+		r, ok := ui.dragRegion(&op.drag)
+		if !ok {
 			return false, nil
 		}
 		op.done = true
-		if !op.drag.Moved() {
-			op.rect = ui.Canvas.Image().Bounds()
-		} else {
-			op.rect = op.drag.Rect()
-		}
+		op.rect = r
 	}
 	if op.spr == nil {
 		op.spr = ui.Canvas.NewSpriteFromRegion(op.rect)
@@ -414,6 +472,18 @@ func (op *FlattenOp) Update(ui *UI) (done bool, err error) {
 	return true, nil
 }
 
+// updates drag until it is released. gives the dragged region, or the whole
+// canvas for a click
+func (ui *UI) dragRegion(drag *MouseDrag) (r image.Rectangle, ok bool) {
+	if !drag.Update() {
+		return image.Rectangle{}, false
+	}
+	if !drag.Moved() {
+		return ui.Canvas.Image().Bounds(), true
+	}
+	return drag.Rect(), true
+}
+
 func (op *FlattenOp) Draw(dst *ebiten.Image) {
 	if !op.drag.Started {
 		return
@@ -584,7 +654,7 @@ func (op *CutOp) Update(ui *UI) (done bool, err error) {
 		return false, nil
 	}
 	for _, sp := range op.Targets {
-		draw.CutImage(sp.Image, op.drag.Rect().Sub(sp.Pos))
+		sp.Cut(op.drag.Rect())
 	}
 	return true, nil
 }
@@ -636,13 +706,7 @@ func (op *OpacityOp) Update(ui *UI) (done bool, err error) {
 	}
 
 	for _, sp := range op.Targets {
-		o := sp.OpacityOffset + op.opacityOffset
-		if o > 0 {
-			o = 0
-		} else if o < -1 {
-			o = -1
-		}
-		sp.OpacityOffset = o
+		sp.AddOpacity(op.opacityOffset)
 	}
 	return true, nil
 }
@@ -692,21 +756,20 @@ func (op *CBCopyOp) Update(ui *UI) (done bool, err error) {
 }
 
 type CBPasteOp struct {
-	spr    *sprite.Sprite
+	group  importGroup
 	setPos bool
 }
 
 func (op *CBPasteOp) String() string { return "paste from clipboard" }
 
 func (op *CBPasteOp) Update(ui *UI) (done bool, err error) {
-	if op.spr == nil {
-		op.spr, err = handlePaste()
-		if err != nil || op.spr == nil {
+	if op.group.sprites == nil {
+		op.group, err = ui.handlePaste()
+		if err != nil || op.group.sprites == nil {
 			return true, err
 		}
-		ui.AddSprite(op.spr)
 	}
-	op.spr.Pos = MousePos()
+	op.group.moveTo(MousePos())
 	if op.setPos || MouseJustPressed(ebiten.MouseButtonLeft) {
 		return true, nil
 	}
@@ -714,8 +777,7 @@ func (op *CBPasteOp) Update(ui *UI) (done bool, err error) {
 }
 
 func (op *CBPasteOp) Draw(dst *ebiten.Image) {
-	if op.spr == nil {
-		return
+	for _, s := range op.group.sprites {
+		s.Draw(dst, image.Point{}, 1)
 	}
-	op.spr.Draw(dst, image.Point{}, 1)
 }
@@ -0,0 +1,216 @@
+package ui
+
+import (
+	"image"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"frame/draw"
+)
+
+var (
+	paletteItems    = 12
+	paletteMinWidth = 240
+	paletteTop      = 40
+	recentLimit     = 10
+)
+
+type paletteEntry struct {
+	name      string
+	operation Operation
+	img       *ebiten.Image
+}
+
+// lists every menu operation, filtered by fuzzy matching the typed text
+type PaletteOp struct {
+	input   TextInput
+	entries []*paletteEntry
+	matches []*paletteEntry
+	query   string
+	index   int
+	width   int
+	fresh   bool
+
+	img    *ebiten.Image
+	imgTxt string
+}
+
+func (op PaletteOp) String() string { return "command palette" }
+
+func NewPalette() *PaletteOp {
+	entries := paletteEntries(mainMenuOptions(), "")
+	width := paletteMinWidth
+	for _, e := range entries {
+		if w := draw.BoundString(draw.Font, e.name).Dx() + menuPadding*2; w > width {
+			width = w
+		}
+	}
+	return &PaletteOp{
+		entries: entries,
+		width:   width,
+		fresh:   true,
+	}
+}
+
+// flattens menu options, submenu entries are prefixed with the submenu name
+func paletteEntries(opts []*MenuOption, prefix string) []*paletteEntry {
+	entries := []*paletteEntry{}
+	for _, opt := range opts {
+		if opt.operation == nil {
+			continue
+		}
+		if m, ok := opt.operation.(*Menu); ok {
+			entries = append(entries, paletteEntries(m.options, prefix+opt.text+": ")...)
+			continue
+		}
+		entries = append(entries, &paletteEntry{
+			name:      prefix + opt.text,
+			operation: opt.operation,
+		})
+	}
+	return entries
+}
+
+func (op *PaletteOp) Update(ui *UI) (done bool, err error) {
+	// skip the key press that opened the palette
+	if op.fresh {
+		op.fresh = false
+		op.filter(ui.recent)
+		return false, nil
+	}
+	submitted := op.input.Update()
+	if op.input.Text != op.query {
+		op.filter(ui.recent)
+	}
+	if len(op.matches) > 0 {
+		if KeyRepeated(ebiten.KeyDown) {
+			op.index = (op.index + 1) % len(op.matches)
+		} else if KeyRepeated(ebiten.KeyUp) {
+			op.index = (op.index + len(op.matches) - 1) % len(op.matches)
+		}
+	}
+	if !submitted || len(op.matches) == 0 {
+		return false, nil
+	}
+	e := op.matches[op.index]
+	ui.addOperation(e.operation)
+	if c := CopyOperation(e.operation); c != nil {
+		ui.lastOp = c
+	}
+	ui.addRecent(e.name)
+	return true, nil
+}
+
+// updates matches for the current input, recently used entries first
+func (op *PaletteOp) filter(recent []string) {
+	op.query = op.input.Text
+	op.index = 0
+	type match struct {
+		*paletteEntry
+		score  int
+		recent int
+	}
+	matches := []match{}
+	for _, e := range op.entries {
+		score, ok := fuzzyMatch(op.query, e.name)
+		if !ok {
+			continue
+		}
+		r := len(recent)
+		for i, name := range recent {
+			if name == e.name {
+				r = i
+				break
+			}
+		}
+		matches = append(matches, match{e, score, r})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].recent != matches[j].recent {
+			return matches[i].recent < matches[j].recent
+		}
+		return matches[i].score > matches[j].score
+	})
+	op.matches = op.matches[:0]
+	for _, m := range matches {
+		op.matches = append(op.matches, m.paletteEntry)
+	}
+}
+
+func (op *PaletteOp) Draw(dst *ebiten.Image) {
+	x := (dst.Bounds().Dx() - op.width) / 2
+	r := image.Rect(0, 0, op.width, menuItemHeight)
+	pos := image.Pt(x, paletteTop)
+	if txt := "> " + op.input.Text + "_"; op.img == nil || txt != op.imgTxt {
+		op.img = draw.NewTextImage(txt, draw.Font, r, menuPadding, menuFg, menuBg)
+		op.imgTxt = txt
+	}
+	draw.DrawImage(dst, op.img, pos, 1)
+	shown := op.matches
+	if len(shown) > paletteItems {
+		shown = shown[:paletteItems]
+	}
+	// keep the highlighted entry visible
+	offset := 0
+	if op.index >= paletteItems {
+		offset = op.index - paletteItems + 1
+		shown = op.matches[offset : offset+paletteItems]
+	}
+	for i, e := range shown {
+		if e.img == nil {
+			e.img = draw.NewTextImage(e.name, draw.Font, r, menuPadding, menuFg, menuBg)
+		}
+		p := pos.Add(image.Pt(0, (i+1)*menuItemHeight))
+		if i+offset == op.index {
+			draw.DrawImageInverted(dst, e.img, p, 1)
+			continue
+		}
+		draw.DrawImage(dst, e.img, p, 1)
+	}
+	outline := image.Rectangle{pos, pos.Add(image.Pt(op.width, (len(shown)+1)*menuItemHeight))}
+	draw.StrokeRect(dst, outline, menuPaddingClr, 2, 2)
+}
+
+// records name as the most recently used palette entry
+func (ui *UI) addRecent(name string) {
+	recent := []string{name}
+	for _, n := range ui.recent {
+		if n != name && len(recent) < recentLimit {
+			recent = append(recent, n)
+		}
+	}
+	ui.recent = recent
+}
+
+// reports whether the runes of pattern appear in order in text, ignoring
+// case. consecutive runes and runes at the start of words score higher
+func fuzzyMatch(pattern, text string) (score int, ok bool) {
+	p := []rune(strings.ToLower(strings.Join(strings.Fields(pattern), "")))
+	t := []rune(strings.ToLower(text))
+	pi := 0
+	prev := -2
+	for ti, r := range t {
+		if pi == len(p) {
+			break
+		}
+		if r != p[pi] {
+			continue
+		}
+		score++
+		if ti == prev+1 {
+			score += 2
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) {
+			score += 3
+		}
+		prev = ti
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	return score, true
+}
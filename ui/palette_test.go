@@ -0,0 +1,32 @@
+package ui
+
+import "testing"
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		text    string
+		ok      bool
+	}{
+		{"", "move", true},
+		{"mv", "move", true},
+		{"BTF", "reorder: bring to front", true},
+		{"cpy clip", "util: copy to clipboard", true},
+		{"vm", "move", false},
+		{"moves", "move", false},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyMatch(tt.pattern, tt.text); ok != tt.ok {
+			t.Errorf("fuzzyMatch(%q, %q) ok = %v, want %v", tt.pattern, tt.text, ok, tt.ok)
+		}
+	}
+}
+
+func TestFuzzyMatch_Ranking(t *testing.T) {
+	// word starts and consecutive runes beat scattered matches
+	a, _ := fuzzyMatch("crop", "crop")
+	b, _ := fuzzyMatch("crop", "util: copy to clipboard")
+	if a <= b {
+		t.Errorf("crop scored %d, copy to clipboard scored %d", a, b)
+	}
+}
@@ -0,0 +1,219 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+	"time"
+
+	"frame/export"
+)
+
+// user settings. main sets them from flags, some can be toggled from the
+// util menu
+type Settings struct {
+	// where images from a watched folder are placed
+	WatchPos image.Point
+	// scale imports down to FitFraction of the canvas size
+	FitImports  bool
+	FitFraction float64
+	// import every frame of an animated gif as a strip of sprites
+	GIFFrames bool
+	// turn jpegs upright as their exif orientation says
+	ExifOrientation bool
+	// exports are saved here under a generated name, if set. otherwise a
+	// file name is asked for
+	ExportDir    string
+	ExportFormat export.Format
+	ExportSize   export.Size
+	// jpeg export quality, 1 to 100
+	JPEGQuality int
+	// pixels around each sprite in an exported atlas
+	AtlasPadding int
+	// svg and html exports inline their images instead of linking pngs
+	EmbedImages bool
+	// page size and dpi of pdf exports
+	PDFPage export.Page
+	// width of timelapse frames, how long each is shown and the longest
+	// an exported timelapse runs, zero for every frame
+	TimelapseWidth  int
+	TimelapseDelay  time.Duration
+	TimelapseLength time.Duration
+}
+
+func DefaultSettings() Settings {
+	return Settings{
+		FitImports:      true,
+		FitFraction:     0.8,
+		ExifOrientation: true,
+		ExportFormat:    export.PNG,
+		ExportSize:      export.Size{Scale: 1},
+		JPEGQuality:     90,
+		AtlasPadding:    2,
+		EmbedImages:     true,
+		PDFPage:         export.Page{Name: "a4", Width: 595.28, Height: 841.89, DPI: 300},
+		TimelapseWidth:  320,
+		TimelapseDelay:  250 * time.Millisecond,
+		TimelapseLength: 20 * time.Second,
+	}
+}
+
+// applies changed settings
+func (ui *UI) SetSettings(s Settings) {
+	ui.Settings = s
+	ui.updateFit()
+	ui.updateDecodeOptions()
+}
+
+// how files are decoded for the current settings. safe to call from any
+// goroutine
+func (ui *UI) decodeOptions() decodeOptions {
+	ui.m.Lock()
+	defer ui.m.Unlock()
+	return ui.decode
+}
+
+// call after the decode settings change
+func (ui *UI) updateDecodeOptions() {
+	opts := decodeOptions{
+		gifFrames: ui.Settings.GIFFrames,
+		orient:    ui.Settings.ExifOrientation,
+	}
+	ui.m.Lock()
+	defer ui.m.Unlock()
+	ui.decode = opts
+}
+
+// largest size an import may have, zero if imports keep their size. safe
+// to call from any goroutine
+func (ui *UI) fitSize() image.Point {
+	ui.m.Lock()
+	defer ui.m.Unlock()
+	return ui.fit
+}
+
+// call after the canvas size or fit settings change
+func (ui *UI) updateFit() {
+	fit := image.Point{}
+	if s := ui.Settings; s.FitImports && s.FitFraction > 0 {
+		fit.X = int(float64(ui.Canvas.Width) * s.FitFraction)
+		fit.Y = int(float64(ui.Canvas.Height) * s.FitFraction)
+	}
+	ui.m.Lock()
+	defer ui.m.Unlock()
+	ui.fit = fit
+}
+
+type FitImportsOp struct{}
+
+func (op FitImportsOp) String() string { return "toggle auto-fit imports" }
+
+func (op *FitImportsOp) Update(ui *UI) (done bool, err error) {
+	ui.Settings.FitImports = !ui.Settings.FitImports
+	ui.updateFit()
+	return true, nil
+}
+
+type GIFFramesOp struct{}
+
+func (op GIFFramesOp) String() string { return "toggle gif frames as strip" }
+
+func (op *GIFFramesOp) Update(ui *UI) (done bool, err error) {
+	ui.Settings.GIFFrames = !ui.Settings.GIFFrames
+	ui.updateDecodeOptions()
+	return true, nil
+}
+
+type ExifOrientationOp struct{}
+
+func (op ExifOrientationOp) String() string { return "toggle exif orientation" }
+
+func (op *ExifOrientationOp) Update(ui *UI) (done bool, err error) {
+	ui.Settings.ExifOrientation = !ui.Settings.ExifOrientation
+	ui.updateDecodeOptions()
+	return true, nil
+}
+
+type EmbedImagesOp struct{}
+
+func (op EmbedImagesOp) String() string { return "toggle embedded web images" }
+
+func (op *EmbedImagesOp) Update(ui *UI) (done bool, err error) {
+	ui.Settings.EmbedImages = !ui.Settings.EmbedImages
+	return true, nil
+}
+
+// asks for the export scale or width
+type ExportSizeOp struct {
+	prompt *PromptOp
+}
+
+func (op ExportSizeOp) String() string { return "set export size" }
+
+func (op *ExportSizeOp) Update(ui *UI) (done bool, err error) {
+	if op.prompt == nil {
+		op.prompt = NewPrompt("export size (2x or 1920px)", ui.Settings.ExportSize.String())
+		ui.addOperation(op.prompt)
+		return false, nil
+	}
+	if !op.prompt.done {
+		return false, nil
+	}
+	size, err := export.ParseSize(op.prompt.Text())
+	if err != nil {
+		return true, err
+	}
+	ui.Settings.ExportSize = size
+	ui.notify("exporting at " + size.String())
+	return true, nil
+}
+
+// asks for the page size and dpi of pdf exports
+type PDFPageOp struct {
+	prompt *PromptOp
+}
+
+func (op PDFPageOp) String() string { return "set pdf page" }
+
+func (op *PDFPageOp) Update(ui *UI) (done bool, err error) {
+	if op.prompt == nil {
+		op.prompt = NewPrompt("pdf page (fit, a3, a4, a5, letter, legal or tabloid) and dpi", ui.Settings.PDFPage.String())
+		ui.addOperation(op.prompt)
+		return false, nil
+	}
+	if !op.prompt.done {
+		return false, nil
+	}
+	page, err := export.ParsePage(op.prompt.Text())
+	if err != nil {
+		return true, err
+	}
+	ui.Settings.PDFPage = page
+	ui.notify("pdf pages are " + page.String())
+	return true, nil
+}
+
+// asks for the padding between sprites in exported atlases
+type AtlasPaddingOp struct {
+	prompt *PromptOp
+}
+
+func (op AtlasPaddingOp) String() string { return "set atlas padding" }
+
+func (op *AtlasPaddingOp) Update(ui *UI) (done bool, err error) {
+	if op.prompt == nil {
+		op.prompt = NewPrompt("atlas padding in pixels", strconv.Itoa(ui.Settings.AtlasPadding))
+		ui.addOperation(op.prompt)
+		return false, nil
+	}
+	if !op.prompt.done {
+		return false, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(op.prompt.Text()))
+	if err != nil || n < 0 {
+		return true, fmt.Errorf("bad padding %q", op.prompt.Text())
+	}
+	ui.Settings.AtlasPadding = n
+	return true, nil
+}
@@ -0,0 +1,201 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"frame/anim"
+	"frame/draw"
+)
+
+var (
+	// recordings are thinned past this many frames
+	timelapseMaxFrames = 1000
+	// frames waiting to be dithered, more are dropped
+	timelapseQueue = 8
+	// the last frame of an exported timelapse is held this long
+	timelapseHold = 2 * time.Second
+)
+
+// records a small frame of the canvas each time an operation completes
+type timelapse struct {
+	*anim.Timelapse
+	recording bool
+	// capture once the canvas is next drawn
+	pending bool
+	frame   *ebiten.Image
+	queue   chan *image.RGBA
+}
+
+// starts a new recording, frames are Settings.TimelapseWidth pixels wide
+func (ui *UI) StartTimelapse() {
+	ui.StopTimelapse()
+	b := ui.Canvas.Image().Bounds()
+	w := ui.Settings.TimelapseWidth
+	if w < 1 || w > b.Dx() {
+		w = b.Dx()
+	}
+	h := int(math.Max(1, math.Round(float64(w*b.Dy())/float64(b.Dx()))))
+	t := &timelapse{
+		Timelapse: anim.NewTimelapse(timelapseMaxFrames),
+		recording: true,
+		pending:   true,
+		frame:     ebiten.NewImage(w, h),
+		queue:     make(chan *image.RGBA, timelapseQueue),
+	}
+	// dithering happens off the game loop
+	go func() {
+		for img := range t.queue {
+			t.Add(img)
+		}
+	}()
+	ui.timelapse = t
+}
+
+// stops recording, the frames are kept for export
+func (ui *UI) StopTimelapse() {
+	if t := ui.timelapse; t != nil && t.recording {
+		t.recording = false
+		close(t.queue)
+	}
+}
+
+func (ui *UI) recordingTimelapse() bool {
+	return ui.timelapse != nil && ui.timelapse.recording
+}
+
+// an operation completed, the canvas is captured after it is drawn
+func (ui *UI) timelapseChanged() {
+	if ui.recordingTimelapse() {
+		ui.timelapse.pending = true
+	}
+}
+
+// scales the canvas into the frame, over white. the canvas is fitted in
+// the middle if the window was resized. reading back the small frame is
+// the only work on the game loop
+func (ui *UI) captureTimelapse() {
+	t := ui.timelapse
+	if !ui.recordingTimelapse() || !t.pending {
+		return
+	}
+	t.pending = false
+	src := ui.Canvas.Image().Bounds()
+	fb := t.frame.Bounds()
+	k := math.Min(float64(fb.Dx())/float64(src.Dx()), float64(fb.Dy())/float64(src.Dy()))
+	size := image.Pt(int(math.Round(float64(src.Dx())*k)), int(math.Round(float64(src.Dy())*k)))
+	dst := image.Rectangle{Max: size}.Add(fb.Size().Sub(size).Div(2))
+	t.frame.Fill(color.White)
+	opts := draw.ReshapeOpts(src, dst)
+	opts.Filter = ebiten.FilterLinear
+	t.frame.DrawImage(ui.Canvas.Image(), &opts)
+	select {
+	case t.queue <- draw.ToRGBA(t.frame):
+	default:
+	}
+}
+
+// the recorded frames cut to the timelapse length and how long each shows
+func (ui *UI) timelapseFrames() ([]image.Image, []time.Duration) {
+	delay := ui.Settings.TimelapseDelay
+	n := 0
+	if l := ui.Settings.TimelapseLength; l > 0 && delay > 0 {
+		n = int(l / delay)
+		if n < 2 {
+			n = 2
+		}
+	}
+	frames := ui.timelapse.Frames(n)
+	delays := make([]time.Duration, len(frames))
+	for i := range delays {
+		delays[i] = delay
+	}
+	delays[len(delays)-1] += timelapseHold
+	return frames, delays
+}
+
+// starts or stops recording a timelapse
+type TimelapseOp struct{}
+
+func (op TimelapseOp) String() string { return "(stop) record timelapse" }
+
+func (op *TimelapseOp) Update(ui *UI) (done bool, err error) {
+	if ui.recordingTimelapse() {
+		ui.StopTimelapse()
+		ui.notify(fmt.Sprintf("timelapse stopped, %d frames", ui.timelapse.Len()))
+		return true, nil
+	}
+	ui.StartTimelapse()
+	ui.notify("recording timelapse")
+	return true, nil
+}
+
+// saves the recorded timelapse as an animated gif
+type ExportTimelapseOp struct {
+	prompt *PromptOp
+}
+
+func (op ExportTimelapseOp) String() string { return "export timelapse" }
+
+func (op *ExportTimelapseOp) Update(ui *UI) (done bool, err error) {
+	if ui.timelapse == nil || ui.timelapse.Len() == 0 {
+		return true, errors.New("no timelapse recorded")
+	}
+	path, ok := ui.exportTarget(&op.prompt, "save timelapse as", "-timelapse", ".gif")
+	if !ok {
+		return false, nil
+	}
+	if path == "" {
+		return true, nil
+	}
+	frames, delays := ui.timelapseFrames()
+	go func() {
+		if err := writeGIF(path, frames, delays); err != nil {
+			ui.notifyError("export timelapse", err)
+			return
+		}
+		ui.notify("saved " + path)
+	}()
+	return true, nil
+}
+
+// asks for the frame delay and the length of exported timelapses
+type TimelapseTimingOp struct {
+	prompt *PromptOp
+}
+
+func (op TimelapseTimingOp) String() string { return "set timelapse timing" }
+
+func (op *TimelapseTimingOp) Update(ui *UI) (done bool, err error) {
+	if op.prompt == nil {
+		text := ui.Settings.TimelapseDelay.String() + " " + ui.Settings.TimelapseLength.String()
+		op.prompt = NewPrompt("timelapse frame delay and length (0 keeps every frame)", text)
+		ui.addOperation(op.prompt)
+		return false, nil
+	}
+	if !op.prompt.done {
+		return false, nil
+	}
+	fields := strings.Fields(op.prompt.Text())
+	if len(fields) != 2 {
+		return true, fmt.Errorf("bad timing %q, want like 250ms 20s", op.prompt.Text())
+	}
+	delay, err := time.ParseDuration(fields[0])
+	if err != nil || delay <= 0 {
+		return true, fmt.Errorf("bad delay %q", fields[0])
+	}
+	length, err := time.ParseDuration(fields[1])
+	if err != nil || length < 0 {
+		return true, fmt.Errorf("bad length %q", fields[1])
+	}
+	ui.Settings.TimelapseDelay = delay
+	ui.Settings.TimelapseLength = length
+	return true, nil
+}
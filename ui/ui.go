@@ -2,15 +2,18 @@ package ui
 
 import (
 	"fmt"
+	"image"
 	"image/color"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 
 	"frame/canvas"
 	"frame/draw"
+	"frame/sprite"
 )
 
 type UI struct {
@@ -19,32 +22,64 @@ type UI struct {
 
 	*canvas.Canvas
 	image *ebiten.Image
-	err   error
 	m     sync.Mutex
 
 	operations []interface{}
 	LockOrder  bool
 	lastOp     Operation
 	status     string
+	selection  []*sprite.Sprite
+	console    *ConsoleOp
+	recent     []string
+	collab     *collabState
+	watcher    *watcher
+	Settings   Settings
+	fit        image.Point
+	decode     decodeOptions
+	imports    []*importJob
+	toasts     []*toast
+	textures   map[textureKey]*ebiten.Image
+	// esc was used this tick and must not cancel operations as well
+	escUsed   bool
+	timelapse *timelapse
+	// animations play from here
+	start time.Time
+
+	linkChecked  time.Time
+	linkBusy     bool
+	sourceMarker *ebiten.Image
+
+	// functions queued from other goroutines, run on the game loop
+	tasks []func(*UI)
 }
 
 func NewUI(w, h int) *UI {
 	c := canvas.NewCanvas(w, h)
 	i := ebiten.NewImage(w, h)
-	return &UI{
+	ui := &UI{
 		Canvas: c,
 		image:  i,
+		start:  time.Now(),
 	}
+	ui.SetSettings(DefaultSettings())
+	return ui
 }
 
 // updates on ticks
 func (ui *UI) Update() error {
-	err := ui.handleDroppedFiles()
-	if err != nil {
-		return err
+	ui.handleDroppedFiles()
+	ui.runTasks()
+	ui.escUsed = false
+	if len(ui.imports) > 0 && inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		ui.cancelImports()
+		ui.escUsed = true
 	}
+	ui.syncCollab()
+	ui.checkLinks()
 
-	if len(ui.operations) == 0 {
+	toastClicked := ui.updateToasts()
+
+	if len(ui.operations) == 0 && !toastClicked {
 		if MouseJustPressed(ebiten.MouseButtonRight) {
 			ui.addOperation(MainMenu(ui))
 		} else if MouseJustPressed(ebiten.MouseButtonLeft) {
@@ -54,15 +89,44 @@ func (ui *UI) Update() error {
 			ui.lastOp = CopyOperation(ui.lastOp)
 		} else if inpututil.IsKeyJustPressed(ebiten.KeyV) && ebiten.IsKeyPressed(ebiten.KeyControl) {
 			ui.addOperation(&CBPasteOp{setPos: true})
+		} else if inpututil.IsKeyJustPressed(ebiten.KeyP) && ebiten.IsKeyPressed(ebiten.KeyControl) {
+			ui.addOperation(NewPalette())
+		} else if inpututil.IsKeyJustPressed(ebiten.KeyBackquote) {
+			ui.addOperation(ui.openConsole())
+		}
+	}
+	// a click that dismissed a toast is not for the operations
+	if !toastClicked {
+		if err := ui.HandleOperations(); err != nil {
+			ui.notifyError("", err)
 		}
 	}
-	ui.HandleOperations()
 	ui.setStatus()
 
+	ui.Canvas.Animate(time.Since(ui.start))
 	ui.Canvas.DrawSprites()
+	ui.captureTimelapse()
 	return nil
 }
 
+// queues f to run on the game loop during the next Update. safe to call
+// from any goroutine
+func (ui *UI) Do(f func(*UI)) {
+	ui.m.Lock()
+	defer ui.m.Unlock()
+	ui.tasks = append(ui.tasks, f)
+}
+
+func (ui *UI) runTasks() {
+	ui.m.Lock()
+	tasks := ui.tasks
+	ui.tasks = nil
+	ui.m.Unlock()
+	for _, f := range tasks {
+		f(ui)
+	}
+}
+
 func (ui *UI) HandleOperations() (err error) {
 	if len(ui.operations) == 0 {
 		ebiten.SetCursorMode(ebiten.CursorModeVisible)
@@ -74,7 +138,7 @@ func (ui *UI) HandleOperations() (err error) {
 		}
 		switch op := ope.(type) {
 		case *Menu:
-			if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			if inpututil.IsKeyJustPressed(ebiten.KeyEscape) && !ui.escUsed {
 				ui.removeOperation(op)
 				continue
 			}
@@ -89,12 +153,15 @@ func (ui *UI) HandleOperations() (err error) {
 			}
 		case Operation:
 			// log.Printf("%T\n", op)
-			if CancelInput() {
+			if CancelInput() && !ui.escUsed {
 				ui.operations = []interface{}{}
 			}
 			if done, e := op.Update(ui); done {
 				ui.removeOperation(op)
-				err = e
+				ui.timelapseChanged()
+				if e != nil {
+					err = fmt.Errorf("%v: %w", op, e)
+				}
 			}
 		default:
 			log.Printf("unhandled operation: %T %#v", op, op)
@@ -109,6 +176,7 @@ func (ui *UI) Layout(newWidth, newHeight int) (int, int) {
 		ui.Width = newWidth
 		ui.Height = newHeight
 		ui.Canvas.Resize(ui.Width, ui.Height)
+		ui.updateFit()
 	}
 	return ui.Width, ui.Height
 }
@@ -128,6 +196,10 @@ func (ui *UI) Draw(screen *ebiten.Image) {
 		}
 	}
 
+	ui.drawSourceMarkers(screen)
+	ui.drawCollab(screen)
+	ui.drawToasts(screen)
+
 	dbgmsg := fmt.Sprintf("%0.f\n", ebiten.ActualFPS())
 	if len(ui.operations) > 0 {
 		for _, o := range ui.operations {
@@ -159,8 +231,25 @@ func (ui *UI) removeOperation(op interface{}) {
 	ui.operations = append(ui.operations[:index], ui.operations[index+1:]...)
 }
 
+// sets the sprites that console commands apply to
+func (ui *UI) setSelection(sprites []*sprite.Sprite) {
+	ui.selection = append([]*sprite.Sprite{}, sprites...)
+}
+
+// returns the selected sprites that are still on the canvas
+func (ui *UI) Selection() []*sprite.Sprite {
+	sel := []*sprite.Sprite{}
+	for _, sp := range ui.selection {
+		if ui.Canvas.Sprites.IndexOf(sp) != -1 {
+			sel = append(sel, sp)
+		}
+	}
+	ui.selection = sel
+	return sel
+}
+
 func (ui *UI) setStatus() {
-	ui.status = ""
+	ui.status = ui.importStatus()
 	if len(ui.operations) == 0 {
 		return
 	}
@@ -171,7 +260,10 @@ func (ui *UI) setStatus() {
 	if _, ok := o.(*Menu); ok {
 		return
 	}
-	ui.status = fmt.Sprintf("%v", o)
+	if ui.status != "" {
+		ui.status += ", "
+	}
+	ui.status += fmt.Sprintf("%v", o)
 	if len(ui.operations) > 1 {
 		for i := 1; i < len(ui.operations); i++ {
 			ui.status += fmt.Sprintf(", %v", ui.operations[i])
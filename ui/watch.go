@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+var watchInterval = time.Second
+
+// polls a folder for new files
+type watcher struct {
+	dir  string
+	stop chan struct{}
+}
+
+// imports images that appear in dir from now on. replaces any folder
+// already being watched
+func (ui *UI) Watch(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	seen, err := listFiles(dir)
+	if err != nil {
+		return err
+	}
+	ui.StopWatching()
+	w := &watcher{
+		dir:  dir,
+		stop: make(chan struct{}),
+	}
+	ui.watcher = w
+	go w.run(ui, seen)
+	log.Println("watching", dir)
+	return nil
+}
+
+func (ui *UI) StopWatching() {
+	if ui.watcher == nil {
+		return
+	}
+	close(ui.watcher.stop)
+	log.Println("stopped watching", ui.watcher.dir)
+	ui.watcher = nil
+}
+
+func (ui *UI) Watching() bool {
+	return ui.watcher != nil
+}
+
+func (w *watcher) run(ui *UI, seen map[string]int64) {
+	// new files are imported once their size stops changing
+	pending := map[string]int64{}
+	// the error last reported, it is only reported again once it changes
+	failing := ""
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-t.C:
+		}
+		files, err := listFiles(w.dir)
+		if err != nil {
+			if err.Error() != failing {
+				failing = err.Error()
+				ui.notifyError("watch", err)
+			}
+			continue
+		}
+		if failing != "" {
+			failing = ""
+			ui.notify("watching " + w.dir + " again")
+		}
+		// a file deleted and created again is new
+		for name := range seen {
+			if _, ok := files[name]; !ok {
+				delete(seen, name)
+			}
+		}
+		for name := range pending {
+			if _, ok := files[name]; !ok {
+				delete(pending, name)
+			}
+		}
+		for name, size := range files {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			if prev, ok := pending[name]; !ok || prev != size || size == 0 {
+				pending[name] = size
+				continue
+			}
+			delete(pending, name)
+			seen[name] = size
+			w.importFile(ui, filepath.Join(w.dir, name))
+		}
+	}
+}
+
+func (w *watcher) importFile(ui *UI, path string) {
+	ims, err := decodeFile(path, ui.decodeOptions())
+	if err != nil {
+		ui.notifyError("watch "+filepath.Base(path), err)
+		return
+	}
+	ims = fitAll(ims, ui.fitSize())
+	ui.Do(func(ui *UI) {
+		ui.addGroup(ims).moveTo(ui.Settings.WatchPos)
+	})
+}
+
+// sizes of the regular, non hidden files in dir
+func listFiles(dir string) (map[string]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]int64{}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files[e.Name()] = fi.Size()
+	}
+	return files, nil
+}
+
+// watches a typed folder, then takes a click for the landing position.
+// stops watching if a folder is already watched
+type WatchFolderOp struct {
+	prompt *PromptOp
+	dir    string
+}
+
+func (op WatchFolderOp) String() string {
+	if op.dir != "" {
+		return "watch folder: click landing position, enter keeps current"
+	}
+	return "(un)watch folder"
+}
+
+func (op *WatchFolderOp) Update(ui *UI) (done bool, err error) {
+	if op.prompt == nil {
+		if ui.Watching() {
+			ui.StopWatching()
+			return true, nil
+		}
+		dir, _ := os.Getwd()
+		op.prompt = NewPrompt("watch folder", dir)
+		ui.addOperation(op.prompt)
+		return false, nil
+	}
+	if !op.prompt.done {
+		return false, nil
+	}
+	if op.dir == "" {
+		op.dir = strings.TrimSpace(op.prompt.Text())
+		if op.dir == "" {
+			return true, nil
+		}
+		return false, nil
+	}
+	if MouseJustPressed(ebiten.MouseButtonLeft) {
+		ui.Settings.WatchPos = MousePos()
+	} else if !inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		return false, nil
+	}
+	return true, ui.Watch(op.dir)
+}